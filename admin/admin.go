@@ -0,0 +1,225 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+var ErrNoToken = errors.New("the admin server needs a token")
+
+// NewHandler returns the admin endpoints, for requests with the Authorization: Bearer token header:
+//
+//	GET  /loglevel       the base level and the overrides of levels
+//	PUT  /loglevel       changes them, {"level": "DEBUG", "overrides": {"/orders.OrderService/": "DEBUG"}},
+//	                     a null override is removed
+//	GET  /buildinfo      the module, version, VCS settings and dependencies of the binary
+//	GET  /services       the services registered on the gRPC server and their methods
+//	     /debug/pprof/   the net/http/pprof profiles
+func NewHandler(token string, levels *Levels, services reflection.ServiceInfoProvider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /loglevel", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, levelsOf(levels))
+	})
+	mux.HandleFunc("PUT /loglevel", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Level     *slog.Level            `json:"level"`
+			Overrides map[string]*slog.Level `json:"overrides"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Level != nil {
+			levels.SetBase(*req.Level)
+		}
+
+		for name, level := range req.Overrides {
+			if level == nil {
+				levels.DeleteOverride(name)
+			} else {
+				levels.SetOverride(name, *level)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, levelsOf(levels))
+	})
+	mux.HandleFunc("GET /buildinfo", func(w http.ResponseWriter, _ *http.Request) {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			http.Error(w, "no build information in the binary", http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, buildInfoOf(info))
+	})
+	mux.HandleFunc("GET /services", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, servicesOf(services))
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return withToken(token, mux)
+}
+
+func withToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type levelsResponse struct {
+	Level     slog.Level            `json:"level"`
+	Overrides map[string]slog.Level `json:"overrides"`
+}
+
+func levelsOf(levels *Levels) levelsResponse {
+	return levelsResponse{Level: levels.Base(), Overrides: levels.Overrides()}
+}
+
+type buildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings"`
+	Deps      []string          `json:"deps"`
+}
+
+func buildInfoOf(info *debug.BuildInfo) buildInfo {
+	result := buildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Version:   info.Main.Version,
+		Settings:  map[string]string{},
+		Deps:      []string{},
+	}
+
+	for _, setting := range info.Settings {
+		result.Settings[setting.Key] = setting.Value
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		result.Deps = append(result.Deps, dep.Path+"@"+dep.Version)
+	}
+
+	return result
+}
+
+type service struct {
+	Name    string   `json:"name"`
+	Methods []method `json:"methods"`
+}
+
+type method struct {
+	Name            string `json:"name"`
+	ClientStreaming bool   `json:"client_streaming"`
+	ServerStreaming bool   `json:"server_streaming"`
+}
+
+func servicesOf(provider reflection.ServiceInfoProvider) []service {
+	services := []service{}
+	if provider == nil {
+		return services
+	}
+
+	for name, info := range provider.GetServiceInfo() {
+		s := service{Name: name, Methods: []method{}}
+		for _, m := range info.Methods {
+			s.Methods = append(s.Methods, method{Name: m.Name, ClientStreaming: m.IsClientStream, ServerStreaming: m.IsServerStream})
+		}
+
+		services = append(services, s)
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	return services
+}
+
+type serverParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *slog.Logger
+	Config    Config
+	Levels    *Levels
+	// Server lists its services on /services, when server.Module is used.
+	Server grpc.ServiceRegistrar `optional:"true"`
+}
+
+func startAdminServer(params serverParams) error {
+	if params.Config.Token() == "" {
+		return ErrNoToken
+	}
+
+	services, _ := params.Server.(reflection.ServiceInfoProvider)
+
+	server := &http.Server{
+		Handler:           NewHandler(params.Config.Token(), params.Levels, services),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", fmt.Sprintf(":%d", params.Config.Port()))
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					params.Logger.Warn(err.Error())
+				}
+			}()
+
+			params.Logger.Info("admin server listening on " + listener.Addr().String())
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	})
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHandler(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
+
+	levels := NewLevels(slog.LevelInfo)
+	handler := NewHandler("secret", levels, grpcServer)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/loglevel", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/loglevel", "wrong", "").Code)
+
+	rec := do(http.MethodGet, "/loglevel", "secret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO","overrides":{}}`, rec.Body.String())
+
+	rec = do(http.MethodPut, "/loglevel", "secret", `{"level":"debug","overrides":{"/svc.S/":"WARN","pkg":"ERROR"}}`)
+	assert.JSONEq(t, `{"level":"DEBUG","overrides":{"/svc.S/":"WARN","pkg":"ERROR"}}`, rec.Body.String())
+
+	rec = do(http.MethodPut, "/loglevel", "secret", `{"overrides":{"pkg":null}}`)
+	assert.JSONEq(t, `{"level":"DEBUG","overrides":{"/svc.S/":"WARN"}}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, levels.Base())
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/loglevel", "secret", `{"level":"loud"}`).Code)
+
+	rec = do(http.MethodGet, "/services", "secret", "")
+
+	var services []service
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &services))
+	require.Len(t, services, 1)
+	assert.Equal(t, "grpc.health.v1.Health", services[0].Name)
+	assert.Contains(t, services[0].Methods, method{Name: "Watch", ServerStreaming: true})
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/debug/pprof/", "secret", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/buildinfo", "secret", "").Code)
+}
@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/disco07/grpc-lib/server"
+)
+
+var ErrTokenRequired = errors.New("is required")
+
+type Config interface {
+	Port() int
+	// Token authenticates the requests, sent as Authorization: Bearer <token>.
+	Token() string
+	// LogLevel is the level the application logs at until it is changed with PUT /loglevel, or by a
+	// configuration reload changing it.
+	LogLevel() slog.Level
+}
+
+type YAMLConfig struct {
+	ValuePort     int    `yaml:"port"`
+	ValueToken    string `yaml:"token"`
+	ValueLogLevel string `yaml:"log_level"`
+}
+
+func (c YAMLConfig) Port() int {
+	return c.ValuePort
+}
+
+func (c YAMLConfig) Token() string {
+	return c.ValueToken
+}
+
+func (c YAMLConfig) LogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.ValueLogLevel))
+
+	return level
+}
+
+// Validate reports every invalid setting, named after its yaml key. An empty configuration is valid,
+// for applications without the admin server.
+func (c YAMLConfig) Validate() error {
+	if c == (YAMLConfig{}) {
+		return nil
+	}
+
+	var errs []error
+
+	if err := server.ValidatePort(c.ValuePort); err != nil {
+		errs = append(errs, fmt.Errorf("port: %w", err))
+	}
+
+	if c.ValueToken == "" {
+		errs = append(errs, fmt.Errorf("token: %w", ErrTokenRequired))
+	}
+
+	if c.ValueLogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.ValueLogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("log_level: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Levels holds the log level of the application and its overrides. An override applies to a gRPC method
+// (/orders.OrderService/Create), a service (/orders.OrderService/) or a Go package and its subpackages
+// (github.com/acme/orders). The most specific override wins.
+type Levels struct {
+	base *slog.LevelVar
+	// lowest is the lowest level enabled anywhere, what slog checks before building a record.
+	lowest *slog.LevelVar
+
+	mu        sync.RWMutex
+	overrides map[string]slog.Level
+}
+
+func NewLevels(level slog.Level) *Levels {
+	l := &Levels{base: &slog.LevelVar{}, lowest: &slog.LevelVar{}, overrides: map[string]slog.Level{}}
+	l.SetBase(level)
+
+	return l
+}
+
+// Level is the lowest level enabled anywhere, so Levels can be the slog.Leveler of a handler.
+func (l *Levels) Level() slog.Level {
+	return l.lowest.Level()
+}
+
+func (l *Levels) Base() slog.Level {
+	return l.base.Level()
+}
+
+func (l *Levels) SetBase(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.base.Set(level)
+	l.updateLowest()
+}
+
+// Overrides returns a copy of the overrides.
+func (l *Levels) Overrides() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	overrides := make(map[string]slog.Level, len(l.overrides))
+	for name, level := range l.overrides {
+		overrides[name] = level
+	}
+
+	return overrides
+}
+
+func (l *Levels) SetOverride(name string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.overrides[name] = level
+	l.updateLowest()
+}
+
+func (l *Levels) DeleteOverride(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.overrides, name)
+	l.updateLowest()
+}
+
+func (l *Levels) updateLowest() {
+	lowest := l.base.Level()
+	for _, level := range l.overrides {
+		lowest = min(lowest, level)
+	}
+
+	l.lowest.Set(lowest)
+}
+
+// levelFor returns the level of the most specific override matching one of names, or the base level.
+func (l *Levels) levelFor(names ...string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	level, best := l.base.Level(), -1
+
+	for key, override := range l.overrides {
+		for _, name := range names {
+			if matches(key, name) && len(key) > best {
+				level, best = override, len(key)
+			}
+		}
+	}
+
+	return level
+}
+
+func matches(key, name string) bool {
+	if !strings.HasPrefix(name, key) {
+		return false
+	}
+
+	return len(name) == len(key) || strings.HasSuffix(key, "/") || name[len(key)] == '/'
+}
+
+// Handler wraps h so records are filtered by l instead of the level h was built with.
+func (l *Levels) Handler(h slog.Handler) slog.Handler {
+	return &levelHandler{Handler: h, levels: l}
+}
+
+type levelHandler struct {
+	slog.Handler
+
+	levels *Levels
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.Level()
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	var names []string
+
+	if method, ok := grpc.Method(ctx); ok {
+		names = append(names, method)
+	}
+
+	if pkg := packageOf(r.PC); pkg != "" {
+		names = append(names, pkg)
+	}
+
+	if r.Level < h.levels.levelFor(names...) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), levels: h.levels}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), levels: h.levels}
+}
+
+// packageOf returns the import path of the package of the function at pc.
+func packageOf(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	name := frame.Function
+
+	// github.com/acme/orders.(*Service).Create: the package ends at the first dot after the last slash.
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+
+	return name
+}
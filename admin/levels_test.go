@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type methodStream struct {
+	grpc.ServerTransportStream
+
+	method string
+}
+
+func (s methodStream) Method() string {
+	return s.method
+}
+
+func TestLevelsHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	levels := NewLevels(slog.LevelWarn)
+	logger := slog.New(levels.Handler(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	logger.Info("dropped")
+	logger.Warn("base level")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "base level")
+
+	levels.SetOverride("/orders.OrderService/", slog.LevelDebug)
+	levels.SetOverride("/orders.OrderService/Delete", slog.LevelError)
+	assert.Equal(t, slog.LevelDebug, levels.Level())
+
+	create := grpc.NewContextWithServerTransportStream(context.Background(), methodStream{method: "/orders.OrderService/Create"})
+	remove := grpc.NewContextWithServerTransportStream(context.Background(), methodStream{method: "/orders.OrderService/Delete"})
+
+	logger.DebugContext(create, "create debug")
+	logger.WarnContext(remove, "delete warn")
+	logger.Debug("other debug")
+	assert.Contains(t, buf.String(), "create debug")
+	assert.NotContains(t, buf.String(), "delete warn")
+	assert.NotContains(t, buf.String(), "other debug")
+
+	levels.SetOverride("github.com/disco07/grpc-lib/admin", slog.LevelInfo)
+	logger.With("k", "v").Info("package info")
+	assert.Contains(t, buf.String(), `msg="package info" k=v`)
+
+	levels.DeleteOverride("/orders.OrderService/")
+	levels.DeleteOverride("/orders.OrderService/Delete")
+	assert.Equal(t, slog.LevelInfo, levels.Level())
+}
+
+func TestMatches(t *testing.T) {
+	assert.True(t, matches("github.com/acme/orders", "github.com/acme/orders"))
+	assert.True(t, matches("github.com/acme/orders", "github.com/acme/orders/internal"))
+	assert.False(t, matches("github.com/acme/orders", "github.com/acme/ordersx"))
+	assert.True(t, matches("/orders.OrderService/", "/orders.OrderService/Create"))
+}
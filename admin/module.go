@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"log/slog"
+
+	"go.uber.org/fx"
+)
+
+// Module serves the admin endpoints (see NewHandler) on a port of their own. The *slog.Logger of the
+// application is routed through the provided *Levels, so its level can be changed while it runs.
+var Module = fx.Options(
+	fx.Provide(
+		newLevels,
+	),
+	fx.Decorate(
+		decorateLogger,
+	),
+	fx.Invoke(
+		startAdminServer,
+	),
+)
+
+func newLevels(config Config) *Levels {
+	return NewLevels(config.LogLevel())
+}
+
+func decorateLogger(logger *slog.Logger, levels *Levels) *slog.Logger {
+	return slog.New(levels.Handler(logger.Handler()))
+}
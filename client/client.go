@@ -3,10 +3,11 @@ package client
 import (
 	"context"
 	"fmt"
-	"github.com/disco07/grpc-lib/marshal"
 	"log"
 	"net/http"
 
+	"github.com/disco07/grpc-lib/marshal"
+	"github.com/disco07/grpc-lib/metadata"
 	"github.com/disco07/grpc-lib/server"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
@@ -15,12 +16,34 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func newGRPCClientConn(lc fx.Lifecycle, grpcServerConfig server.GRPCConfigServer) (*grpc.ClientConn, error) {
-	conn, err := grpc.NewClient(
-		grpcServerConfig.Host(),
+// dialOptionsGroup is the fx value group of the extra options of the connection to the gRPC server.
+const dialOptionsGroup = `group:"dial_options"`
+
+// WithDialOption adds an option to the connection of the gateway to the gRPC server.
+func WithDialOption(opt grpc.DialOption) fx.Option {
+	return fx.Provide(fx.Annotate(
+		func() grpc.DialOption { return opt },
+		fx.ResultTags(dialOptionsGroup),
+	))
+}
+
+type clientConnParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Config    server.GRPCConfigServer
+	Options   []grpc.DialOption `group:"dial_options"`
+}
+
+func newGRPCClientConn(params clientConnParams) (*grpc.ClientConn, error) {
+	lc := params.Lifecycle
+
+	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
-	)
+	}
+
+	conn, err := grpc.NewClient(params.Config.Host(), append(opts, params.Options...)...)
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to order service: %w", err)
 	}
@@ -35,10 +58,64 @@ func newGRPCClientConn(lc fx.Lifecycle, grpcServerConfig server.GRPCConfigServer
 	return conn, nil
 }
 
-func newServeMux() *runtime.ServeMux {
-	return runtime.NewServeMux(
-		marshal.WithMultipartFormMarshaler(),
-	)
+// serveMuxOptionsGroup is the fx value group of the extra options applied to the gateway mux.
+const serveMuxOptionsGroup = `group:"serve_mux_options"`
+
+// responseFormatsGroup is the fx value group of the response formats negotiated by the gateway.
+const responseFormatsGroup = `group:"response_formats"`
+
+// WithServeMuxOption adds an option to the gateway mux built by Module.
+func WithServeMuxOption(opt runtime.ServeMuxOption) fx.Option {
+	return fx.Provide(fx.Annotate(
+		func() runtime.ServeMuxOption { return opt },
+		fx.ResultTags(serveMuxOptionsGroup),
+	))
+}
+
+// WithResponseFormats lets the gateway answer in formats besides JSON, picked from the Accept header
+// (see marshal.WithContentNegotiation), with the JSON options of the config.
+func WithResponseFormats(formats ...marshal.Format) fx.Option {
+	return fx.Provide(fx.Annotate(
+		func() []marshal.Format { return formats },
+		fx.ResultTags(responseFormatsGroup),
+	))
+}
+
+type serveMuxParams struct {
+	fx.In
+
+	Config  GRPCConfigClient
+	Options []runtime.ServeMuxOption `group:"serve_mux_options"`
+	Formats [][]marshal.Format       `group:"response_formats"`
+}
+
+func newServeMux(params serveMuxParams) *runtime.ServeMux {
+	marshalers := marshal.Marshalers{JSON: marshal.DefaultJSONOptions()}
+	if config, ok := params.Config.(JSONConfigClient); ok {
+		marshalers.JSON = config.JSON()
+	}
+
+	opts := []runtime.ServeMuxOption{
+		marshalers.Default(),
+		marshalers.MultipartForm(),
+		marshalers.MultipartMixed(),
+		marshalers.FormURLEncoded(),
+		marshalers.HTTPBodyDownloads(),
+		marshal.WithHTTPStatus(),
+		metadata.WithForwardedHeaders(),
+		metadata.WithOutgoingHeaderMatcher(),
+	}
+
+	if len(params.Formats) > 0 {
+		var formats []marshal.Format
+		for _, f := range params.Formats {
+			formats = append(formats, f...)
+		}
+
+		opts = append(opts, marshalers.ContentNegotiation(formats...))
+	}
+
+	return runtime.NewServeMux(append(opts, params.Options...)...)
 }
 
 func startHTTPClient(lc fx.Lifecycle, mux *runtime.ServeMux, config GRPCConfigClient) {
@@ -48,7 +125,7 @@ func startHTTPClient(lc fx.Lifecycle, mux *runtime.ServeMux, config GRPCConfigCl
 				fmt.Println("API gateway server is running on " + fmt.Sprintf(":%d", config.Port()))
 				if err := http.ListenAndServe(
 					fmt.Sprintf(":%d", config.Port()),
-					withCORS(mux),
+					Handler(mux),
 				); err != nil {
 					log.Fatalf("gateway server closed abruptly: %v", err)
 				}
@@ -1,13 +1,60 @@
 package client
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/disco07/grpc-lib/marshal"
+	"github.com/disco07/grpc-lib/server"
+)
+
 type GRPCConfigClient interface {
 	Port() int
 }
 
+// JSONConfigClient is implemented by the GRPCConfigClient setting the JSON mapping used by every
+// marshaler of the gateway, marshal.DefaultJSONOptions otherwise.
+type JSONConfigClient interface {
+	JSON() marshal.JSONOptions
+}
+
 type YAMLGRPCConfigClient struct {
-	ValuePort int `yaml:"port"`
+	ValuePort int            `yaml:"port"`
+	ValueJSON YAMLJSONConfig `yaml:"json"`
 }
 
 func (c YAMLGRPCConfigClient) Port() int {
 	return c.ValuePort
 }
+
+func (c YAMLGRPCConfigClient) JSON() marshal.JSONOptions {
+	opts := marshal.DefaultJSONOptions()
+	opts.UseProtoNames = c.ValueJSON.UseProtoNames
+	opts.UseEnumNumbers = c.ValueJSON.UseEnumNumbers
+	opts.RejectUnknownFields = c.ValueJSON.RejectUnknownFields
+
+	if c.ValueJSON.EmitUnpopulated != nil {
+		opts.EmitUnpopulated = *c.ValueJSON.EmitUnpopulated
+	}
+
+	return opts
+}
+
+// Validate reports every invalid setting, named after its yaml key.
+func (c YAMLGRPCConfigClient) Validate() error {
+	var errs []error
+
+	if err := server.ValidatePort(c.ValuePort); err != nil {
+		errs = append(errs, fmt.Errorf("port: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// YAMLJSONConfig holds the JSON settings of the gateway, left out settings keep marshal.DefaultJSONOptions.
+type YAMLJSONConfig struct {
+	UseProtoNames       bool  `yaml:"use_proto_names"`
+	EmitUnpopulated     *bool `yaml:"emit_unpopulated"`
+	UseEnumNumbers      bool  `yaml:"use_enum_numbers"`
+	RejectUnknownFields bool  `yaml:"reject_unknown_fields"`
+}
@@ -1,14 +1,58 @@
 package client
 
 import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/disco07/grpc-lib/tus"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/cors"
-	"net/http"
 )
 
-func withCORS(mux *runtime.ServeMux) http.Handler {
-	withCors := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
+var (
+	allowedOrigins atomic.Pointer[[]string]
+	corsHandler    atomic.Pointer[cors.Cors]
+)
+
+func init() {
+	SetAllowedOrigins("*")
+}
+
+// SetAllowedOrigins replaces the origins allowed by the gateway, "*" allowing every origin. An origin may
+// hold one wildcard, as in https://*.example.com. It can be called while serving.
+func SetAllowedOrigins(origins ...string) {
+	lower := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		lower = append(lower, strings.ToLower(origin))
+	}
+
+	allowedOrigins.Store(&lower)
+	corsHandler.Store(cors.New(corsOptions(lower)))
+}
+
+func isAllowedOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+
+	for _, allowed := range *allowedOrigins.Load() {
+		prefix, suffix, wildcard := strings.Cut(allowed, "*")
+		if !wildcard && allowed == origin {
+			return true
+		}
+
+		if wildcard && len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsOptions allows every origin with a literal Access-Control-Allow-Origin: *, which browsers refuse
+// for credentialed requests, when origins holds "*". Only the origins of an explicit list are echoed back.
+func corsOptions(origins []string) cors.Options {
+	options := cors.Options{
 		AllowedMethods: []string{
 			http.MethodGet,
 			http.MethodPost,
@@ -17,11 +61,24 @@ func withCORS(mux *runtime.ServeMux) http.Handler {
 			http.MethodDelete,
 			http.MethodPatch,
 		},
-		AllowedHeaders:   []string{"ACCEPT", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   append([]string{"ACCEPT", "Authorization", "Content-Type", "X-CSRF-Token"}, tus.Headers...),
+		ExposedHeaders:   append([]string{"Link"}, tus.Headers...),
 		AllowCredentials: true,
 		MaxAge:           300,
-	}).Handler(mux)
+	}
+
+	if slices.Contains(origins, "*") {
+		options.AllowedOrigins = []string{"*"}
+	} else {
+		options.AllowOriginFunc = isAllowedOrigin
+	}
+
+	return options
+}
 
-	return withCors
+// Handler returns the gateway as served by Module, mux behind the CORS handler.
+func Handler(mux *runtime.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corsHandler.Load().ServeHTTP(w, r, mux.ServeHTTP)
+	})
 }
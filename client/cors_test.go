@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAllowedOrigins(t *testing.T) {
+	defer SetAllowedOrigins("*")
+
+	assert.True(t, isAllowedOrigin("https://anything.test"))
+
+	SetAllowedOrigins("https://app.example.com", "https://*.preview.example.com")
+
+	assert.True(t, isAllowedOrigin("https://app.example.com"))
+	assert.True(t, isAllowedOrigin("https://PR-12.preview.example.com"))
+	assert.False(t, isAllowedOrigin("https://preview.example.com"))
+	assert.False(t, isAllowedOrigin("https://evil.com"))
+}
+
+func TestHandlerCORSHeaders(t *testing.T) {
+	defer SetAllowedOrigins("*")
+
+	handler := Handler(runtime.NewServeMux())
+	request := func(origin string) http.Header {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Header()
+	}
+
+	// Every origin may read responses, but browsers don't send credentials to a literal *.
+	headers := request("https://evil.com")
+	assert.Equal(t, "*", headers.Get("Access-Control-Allow-Origin"))
+
+	SetAllowedOrigins("https://app.example.com")
+
+	headers = request("https://app.example.com")
+	assert.Equal(t, "https://app.example.com", headers.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", headers.Get("Access-Control-Allow-Credentials"))
+
+	headers = request("https://evil.com")
+	assert.Empty(t, headers.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, headers.Get("Access-Control-Allow-Credentials"))
+}
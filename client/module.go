@@ -5,13 +5,20 @@ import (
 	"go.uber.org/fx"
 )
 
-var Module = fx.Options(
+// Gateway provides the connection to the gRPC server and the gateway mux, without serving it.
+var Gateway = fx.Options(
 	fx.Provide(
 		newGRPCClientConn,
 		newServeMux,
 	),
 	fx.Invoke(
-		startHTTPClient,
 		health.RegisterHealthServiceHandler,
 	),
 )
+
+var Module = fx.Options(
+	Gateway,
+	fx.Invoke(
+		startHTTPClient,
+	),
+)
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/disco07/grpc-lib/admin"
+	"github.com/disco07/grpc-lib/client"
+	"github.com/disco07/grpc-lib/metadata"
+	"github.com/disco07/grpc-lib/server"
+	"go.uber.org/fx"
+)
+
+// Config is the configuration of server.Module and client.Module:
+//
+//	server:
+//	  host: localhost:50051
+//	  port: 50051
+//	client:
+//	  port: 8080
+//	  json:
+//	    use_proto_names: true
+//	admin:
+//	  port: 9090
+//	  token: secret
+//	runtime:
+//	  cors_origins: [https://*.example.com]
+type Config struct {
+	Server server.YAMLGRPCConfigServer `yaml:"server"`
+	Client client.YAMLGRPCConfigClient `yaml:"client"`
+	// Admin is only needed with admin.Module.
+	Admin admin.YAMLConfig `yaml:"admin"`
+	// Runtime and admin.log_level are applied again on every reload, the other settings only at startup.
+	Runtime Runtime `yaml:"runtime"`
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	for _, e := range flatten(c.Server.Validate()) {
+		errs = append(errs, fmt.Errorf("server.%w", e))
+	}
+
+	for _, e := range flatten(c.Client.Validate()) {
+		errs = append(errs, fmt.Errorf("client.%w", e))
+	}
+
+	for _, e := range flatten(c.Admin.Validate()) {
+		errs = append(errs, fmt.Errorf("admin.%w", e))
+	}
+
+	for _, e := range flatten(c.Runtime.Validate()) {
+		errs = append(errs, fmt.Errorf("runtime.%w", e))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Runtime holds the settings that can change while the process runs.
+type Runtime struct {
+	// LoggingSkip lists the methods server.LoggingInterceptor doesn't log, server.DefaultLoggingSkip when empty.
+	LoggingSkip []string `yaml:"logging_skip"`
+	// CORSOrigins lists the origins allowed by the gateway, every origin when empty.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// TrustedProxies lists the proxies believed when resolving client IPs, metadata.DefaultTrustedProxies when empty.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+func (r Runtime) Validate() error {
+	if _, err := metadata.NewIPResolver(r.TrustedProxies...); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+
+	return nil
+}
+
+// Apply hands the settings to the components using them.
+func (r Runtime) Apply() {
+	skip := r.LoggingSkip
+	if len(skip) == 0 {
+		skip = server.DefaultLoggingSkip
+	}
+
+	server.SetLoggingSkip(skip...)
+
+	origins := r.CORSOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
+	client.SetAllowedOrigins(origins...)
+
+	proxies := r.TrustedProxies
+	if len(proxies) == 0 {
+		proxies = metadata.DefaultTrustedProxies
+	}
+
+	// Validate already parsed them.
+	_ = metadata.SetTrustedProxies(proxies...)
+}
+
+// Module loads Config from the sources of opts when the application starts, failing with every
+// problem found, and provides it along with server.GRPCConfigServer, client.GRPCConfigClient and admin.Config.
+// While the application runs, the configuration is reloaded on SIGHUP and when its file changes,
+// Runtime is applied again and, with admin.Module, a changed admin.log_level becomes the base level.
+// Subscribe to *Watcher[Config] to follow other settings.
+func Module(opts ...Option) fx.Option {
+	return fx.Options(
+		fx.Provide(
+			func(logger *slog.Logger) (*Watcher[Config], error) {
+				return NewWatcher[Config](logger, opts...)
+			},
+			func(watcher *Watcher[Config]) *Config { return watcher.Current() },
+			func(config *Config) server.GRPCConfigServer { return config.Server },
+			func(config *Config) client.GRPCConfigClient { return config.Client },
+			func(config *Config) admin.Config { return config.Admin },
+		),
+		fx.Invoke(watch),
+	)
+}
+
+type watchParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Watcher   *Watcher[Config]
+	// Levels is only provided by admin.Module.
+	Levels *admin.Levels `optional:"true"`
+}
+
+func watch(params watchParams) {
+	params.Watcher.Subscribe(func(config *Config) {
+		config.Runtime.Apply()
+	})
+
+	if params.Levels != nil {
+		followLogLevel(params.Watcher, params.Levels)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go params.Watcher.Run(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+// followLogLevel sets the base level of levels when a reload changes admin.log_level, leaving the
+// level set with PUT /loglevel alone otherwise.
+func followLogLevel(watcher *Watcher[Config], levels *admin.Levels) {
+	level := watcher.Current().Admin.LogLevel()
+
+	watcher.Subscribe(func(config *Config) {
+		if reloaded := config.Admin.LogLevel(); reloaded != level {
+			level = reloaded
+			levels.SetBase(reloaded)
+		}
+	})
+}
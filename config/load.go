@@ -0,0 +1,389 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidConfig   = errors.New("invalid configuration")
+	ErrUnsupportedType = errors.New("unsupported setting type")
+)
+
+// Validator is implemented by configurations checking their own values. Validate should report
+// every problem, e.g. with errors.Join.
+type Validator interface {
+	Validate() error
+}
+
+// Option adds a source to Load. Later sources override earlier ones, whatever the order of the options:
+// the YAML file, then environment variables, then flags.
+type Option func(*loader)
+
+type loader struct {
+	file      string
+	envPrefix string
+	env       bool
+	args      []string
+	flags     bool
+	interval  time.Duration
+}
+
+// File reads the YAML file at path. Unknown keys are errors, so typos don't go unnoticed.
+func File(path string) Option {
+	return func(l *loader) {
+		l.file = path
+	}
+}
+
+// Env reads environment variables named after the yaml keys, upper cased and joined with
+// underscores after prefix: APP_SERVER_PORT for server.port with the APP prefix.
+func Env(prefix string) Option {
+	return func(l *loader) {
+		l.env = true
+		l.envPrefix = prefix
+	}
+}
+
+// Flags parses args, usually os.Args[1:], for flags named after the yaml keys: -server.port=8080.
+// Other flags, with the value following them, and arguments are left to the application.
+func Flags(args []string) Option {
+	return func(l *loader) {
+		l.flags = true
+		l.args = args
+	}
+}
+
+// Load fills dst, a pointer to a struct with yaml tags, from the sources of opts, then validates it when
+// it is a Validator. Every problem found is reported in one ErrInvalidConfig error. The values already in
+// dst are the defaults.
+func Load(dst any, opts ...Option) error {
+	l := &loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrUnsupportedType, dst)
+	}
+
+	var errs []error
+
+	if l.file != "" {
+		if err := loadFile(l.file, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	settings, err := collectSettings(v.Elem(), nil)
+	if err != nil {
+		return err
+	}
+
+	if l.env {
+		errs = append(errs, l.loadEnv(settings)...)
+	}
+
+	if l.flags {
+		errs = append(errs, l.loadFlags(settings)...)
+	}
+
+	if validator, ok := dst.(Validator); ok {
+		errs = append(errs, flatten(validator.Validate())...)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func loadFile(path string, dst any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (l *loader) loadEnv(settings []setting) []error {
+	var errs []error
+
+	for _, s := range settings {
+		name := strings.ToUpper(strings.Join(s.path, "_"))
+		if l.envPrefix != "" {
+			name = strings.ToUpper(l.envPrefix) + "_" + name
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errs
+}
+
+func (l *loader) loadFlags(settings []setting) []error {
+	var errs []error
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	for _, s := range settings {
+		name := strings.Join(s.path, ".")
+
+		// Bad values are collected rather than returned, so parsing goes on to the next flags.
+		set := func(value string) error {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", name, err))
+			}
+
+			return nil
+		}
+
+		if s.isBool() {
+			fs.BoolFunc(name, "", set)
+		} else {
+			fs.Func(name, "", set)
+		}
+	}
+
+	if err := fs.Parse(knownFlags(fs, l.args)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// knownFlags returns the flags of args defined in fs. An unknown flag without =value is taken to be
+// followed by its value unless the next argument is a flag.
+func knownFlags(fs *flag.FlagSet, args []string) []string {
+	var known []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		f := fs.Lookup(name)
+		if f == nil {
+			if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+			}
+
+			continue
+		}
+
+		known = append(known, arg)
+
+		if isBool, _ := f.Value.(interface{ IsBoolFlag() bool }); isBool != nil && isBool.IsBoolFlag() {
+			continue
+		}
+
+		if !hasValue && i+1 < len(args) {
+			i++
+			known = append(known, args[i])
+		}
+	}
+
+	return known
+}
+
+// setting is a leaf of the configuration struct, reachable by the yaml keys of path.
+type setting struct {
+	path  []string
+	value reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func collectSettings(v reflect.Value, path []string) ([]setting, error) {
+	var settings []setting
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fieldPath := append(append([]string(nil), path...), name)
+		fieldVal := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			nested, err := collectSettings(fieldVal, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+
+			settings = append(settings, nested...)
+
+			continue
+		}
+
+		s := setting{path: fieldPath, value: fieldVal}
+		if err := s.check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(fieldPath, "."), err)
+		}
+
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+func (s setting) leafType() reflect.Type {
+	t := s.value.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func (s setting) isBool() bool {
+	return s.leafType().Kind() == reflect.Bool
+}
+
+func (s setting) check() error {
+	t := s.leafType()
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+}
+
+// set parses value into the setting. Lists are comma separated.
+func (s setting) set(value string) error {
+	v := s.value
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		v.Set(ptr)
+
+		return nil
+	}
+
+	return setValue(v, value)
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse bool: %w", errors.Unwrap(err))
+		}
+
+		v.SetBool(b)
+	case v.CanInt():
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse int: %w", errors.Unwrap(err))
+		}
+
+		v.SetInt(i)
+	case v.CanUint():
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse uint: %w", errors.Unwrap(err))
+		}
+
+		v.SetUint(u)
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("failed to parse float: %w", errors.Unwrap(err))
+		}
+
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice:
+		var items []string
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	}
+
+	return nil
+}
+
+// flatten splits errors joined by Validate so each problem is listed on its own.
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // only the top level is split.
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
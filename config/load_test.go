@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disco07/grpc-lib/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeFile(t, `
+server:
+  host: localhost:50051
+  port: 50051
+client:
+  port: 8080
+  json:
+    use_proto_names: true
+`)
+
+	t.Setenv("APP_SERVER_PORT", "50052")
+	t.Setenv("APP_CLIENT_JSON_EMIT_UNPOPULATED", "false")
+
+	config := &Config{}
+	require.NoError(t, Load(config, File(path), Env("app"), Flags([]string{"-client.port=9090", "-client.json.use_enum_numbers"})))
+
+	assert.Equal(t, "localhost:50051", config.Server.Host())
+	assert.Equal(t, 50052, config.Server.Port())
+	assert.Equal(t, 9090, config.Client.Port())
+
+	json := config.Client.JSON()
+	assert.True(t, json.UseProtoNames)
+	assert.False(t, json.EmitUnpopulated)
+	assert.True(t, json.UseEnumNumbers)
+}
+
+func TestLoadSkipsApplicationFlags(t *testing.T) {
+	path := writeFile(t, "server:\n  host: localhost:50051\n  port: 50051\nclient:\n  port: 8080\n")
+
+	args := []string{
+		"-verbose", "--workers", "4", "-client.port", "9090", "-mode=fast",
+		"serve", "--server.port=50052", "-client.json.use_enum_numbers", "--", "-client.port=1",
+	}
+
+	config := &Config{}
+	require.NoError(t, Load(config, File(path), Flags(args)))
+
+	assert.Equal(t, 50052, config.Server.Port())
+	assert.Equal(t, 9090, config.Client.Port())
+	assert.True(t, config.Client.JSON().UseEnumNumbers)
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	path := writeFile(t, "server:\n  port: 70000\n")
+
+	t.Setenv("APP_CLIENT_PORT", "http")
+
+	err := Load(&Config{}, File(path), Env("APP"), Flags([]string{"-client.json.use_proto_names=maybe"}))
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	require.ErrorIs(t, err, server.ErrInvalidPort)
+	require.ErrorIs(t, err, server.ErrHostRequired)
+
+	assert.Contains(t, err.Error(), "APP_CLIENT_PORT: failed to parse int: invalid syntax")
+	assert.Contains(t, err.Error(), "-client.json.use_proto_names: failed to parse bool: invalid syntax")
+	assert.Contains(t, err.Error(), "server.port: must be between 1 and 65535, got 70000")
+	assert.Contains(t, err.Error(), "server.host: is required")
+	assert.Contains(t, err.Error(), "client.port: must be between 1 and 65535, got 0")
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "server:\n  prot: 50051\n")
+
+	err := Load(&Config{}, File(path))
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "field prot not found")
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// WatchInterval sets how often a Watcher checks its file for changes.
+func WatchInterval(interval time.Duration) Option {
+	return func(l *loader) {
+		l.interval = interval
+	}
+}
+
+// Watcher loads a configuration like Load, then reloads it on SIGHUP and when its file changes.
+// Each valid snapshot is published to the subscribers, an invalid one is logged and dropped.
+type Watcher[T any] struct {
+	opts     []Option
+	file     string
+	interval time.Duration
+	logger   *slog.Logger
+	reloads  metric.Int64Counter
+
+	current atomic.Pointer[T]
+
+	mu          sync.Mutex
+	subscribers []func(*T)
+	modTime     time.Time
+	size        int64
+}
+
+// NewWatcher loads the first snapshot, failing like Load.
+func NewWatcher[T any](logger *slog.Logger, opts ...Option) (*Watcher[T], error) {
+	l := &loader{interval: defaultWatchInterval}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	reloads, err := otel.Meter("github.com/disco07/grpc-lib/config").Int64Counter(
+		"config.reloads",
+		metric.WithDescription("Configuration reloads, by result."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher[T]{opts: opts, file: l.file, interval: l.interval, logger: logger, reloads: reloads}
+	w.modTime, w.size = w.stat()
+
+	snapshot := new(T)
+	if err := Load(snapshot, opts...); err != nil {
+		return nil, err
+	}
+
+	w.current.Store(snapshot)
+
+	return w, nil
+}
+
+// Current returns the last valid snapshot. It must not be modified.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe calls fn with the current snapshot, then with every new one. Calls are never concurrent.
+func (w *Watcher[T]) Subscribe(fn func(*T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+	fn(w.current.Load())
+}
+
+// Reload loads a new snapshot and publishes it. When it is invalid, the current snapshot is kept
+// and the error returned.
+func (w *Watcher[T]) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.modTime, w.size = w.stat()
+
+	snapshot := new(T)
+	if err := Load(snapshot, w.opts...); err != nil {
+		w.logger.Error("configuration reload failed, keeping the current configuration", slog.Any("error", err))
+		w.reloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "failure")))
+
+		return err
+	}
+
+	w.current.Store(snapshot)
+
+	for _, fn := range w.subscribers {
+		fn(snapshot)
+	}
+
+	w.logger.Info("configuration reloaded")
+	w.reloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "success")))
+
+	return nil
+}
+
+// Run reloads the configuration on SIGHUP and when its file changes, until ctx is done.
+func (w *Watcher[T]) Run(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			_ = w.Reload()
+		case <-ticker.C:
+			if w.changed() {
+				_ = w.Reload()
+			}
+		}
+	}
+}
+
+func (w *Watcher[T]) changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	modTime, size := w.stat()
+
+	return !modTime.Equal(w.modTime) || size != w.size
+}
+
+func (w *Watcher[T]) stat() (time.Time, int64) {
+	if w.file == "" {
+		return time.Time{}, 0
+	}
+
+	info, err := os.Stat(w.file)
+	if err != nil {
+		return time.Time{}, -1
+	}
+
+	return info.ModTime(), info.Size()
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/disco07/grpc-lib/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+server:
+  host: localhost:50051
+  port: 50051
+client:
+  port: 8080
+runtime:
+`
+
+func TestWatcherReload(t *testing.T) {
+	path := writeFile(t, validConfig+"  cors_origins: [https://a.example.com]\n")
+
+	watcher, err := NewWatcher[Config](slog.New(slog.NewTextHandler(io.Discard, nil)), File(path), WatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	snapshots := make(chan *Config, 4)
+	watcher.Subscribe(func(c *Config) { snapshots <- c })
+	assert.Equal(t, []string{"https://a.example.com"}, (<-snapshots).Runtime.CORSOrigins)
+
+	require.NoError(t, os.WriteFile(path, []byte(validConfig+"  logging_skip: [/svc/Ping]\n"), 0o600))
+	require.NoError(t, watcher.Reload())
+	assert.Equal(t, []string{"/svc/Ping"}, (<-snapshots).Runtime.LoggingSkip)
+
+	require.NoError(t, os.WriteFile(path, []byte(validConfig+"  trusted_proxies: [not-an-ip]\n"), 0o600))
+	require.ErrorIs(t, watcher.Reload(), ErrInvalidConfig)
+	assert.Empty(t, snapshots)
+	assert.Equal(t, []string{"/svc/Ping"}, watcher.Current().Runtime.LoggingSkip)
+}
+
+func TestWatcherRunReloadsChangedFile(t *testing.T) {
+	path := writeFile(t, validConfig)
+
+	watcher, err := NewWatcher[Config](slog.New(slog.NewTextHandler(io.Discard, nil)), File(path), WatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	assert.Empty(t, watcher.Current().Runtime.CORSOrigins)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go watcher.Run(ctx)
+
+	require.NoError(t, os.WriteFile(path, []byte(validConfig+"  cors_origins: [https://b.example.com]\n"), 0o600))
+
+	assert.Eventually(t, func() bool {
+		origins := watcher.Current().Runtime.CORSOrigins
+		return len(origins) == 1 && origins[0] == "https://b.example.com"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherReloadsLogLevel(t *testing.T) {
+	path := writeFile(t, validConfig+"admin:\n  port: 9090\n  token: secret\n  log_level: info\n")
+
+	watcher, err := NewWatcher[Config](slog.New(slog.NewTextHandler(io.Discard, nil)), File(path), WatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	levels := admin.NewLevels(watcher.Current().Admin.LogLevel())
+	followLogLevel(watcher, levels)
+	assert.Equal(t, slog.LevelInfo, levels.Base())
+
+	require.NoError(t, os.WriteFile(path, []byte(validConfig+"admin:\n  port: 9090\n  token: secret\n  log_level: debug\n"), 0o600))
+	require.NoError(t, watcher.Reload())
+	assert.Equal(t, slog.LevelDebug, levels.Base())
+
+	// A level set at runtime survives reloads leaving log_level alone.
+	levels.SetBase(slog.LevelWarn)
+	require.NoError(t, watcher.Reload())
+	assert.Equal(t, slog.LevelWarn, levels.Base())
+}
@@ -0,0 +1,117 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxErrorValueLen bounds the offending value echoed in a FieldError, in bytes.
+const maxErrorValueLen = 64
+
+// FieldError describes a form field that could not be bound.
+type FieldError struct {
+	// Field is the form key of the field, e.g. items[0].qty.
+	Field string
+	// Type is the Go type of the target, e.g. int or time.Time.
+	Type string
+	// Value is the offending value, truncated, or the filename of a file.
+	Value string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s (%s) = %q: %v", e.Field, e.Type, e.Value, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+func newFieldError(node *formNode, typ reflect.Type, path string, err error) FieldError {
+	fieldErr := FieldError{Field: path, Type: typ.String(), Err: err}
+
+	if len(node.values) > 0 {
+		switch v := node.values[0].(type) {
+		case string:
+			fieldErr.Value = v
+		case *multipart.FileHeader:
+			fieldErr.Value = v.Filename
+		}
+	}
+
+	if len(fieldErr.Value) > maxErrorValueLen {
+		fieldErr.Value = truncateUTF8(fieldErr.Value, maxErrorValueLen) + "..."
+	}
+
+	return fieldErr
+}
+
+// BindingError lists every form field that could not be bound by ParseMultipartForm.
+type BindingError struct {
+	Fields []FieldError
+}
+
+func (e *BindingError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+
+	return "binding failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap exposes the causes, so errors.Is and errors.As see through a BindingError.
+func (e *BindingError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+
+	return errs
+}
+
+// GRPCStatus converts the error to an InvalidArgument status with one field violation per field.
+func (e *BindingError) GRPCStatus() *status.Status {
+	return badRequestStatus(e.Error(), e.fieldViolations())
+}
+
+// failed reports whether path, or the field holding it, could not be bound.
+func (e *BindingError) failed(path string) bool {
+	for _, f := range e.Fields {
+		if path == f.Field || strings.HasPrefix(path, f.Field+".") || strings.HasPrefix(path, f.Field+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *BindingError) fieldViolations() []*errdetails.BadRequest_FieldViolation {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		desc := fmt.Sprintf("invalid %s %q: %v", f.Type, f.Value, f.Err)
+		if errors.Is(f.Err, ErrRequired) {
+			desc = f.Err.Error()
+		}
+
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: desc})
+	}
+
+	return violations
+}
+
+func badRequestStatus(msg string, violations []*errdetails.BadRequest_FieldViolation) *status.Status {
+	st := status.New(codes.InvalidArgument, msg)
+	if withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		return withDetails
+	}
+
+	return st
+}
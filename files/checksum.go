@@ -0,0 +1,361 @@
+package files
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec // MD5 is only used to check client supplied Content-MD5 digests.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// HashAlgorithm names a digest algorithm using its RFC 9530 (Content-Digest) identifier.
+type HashAlgorithm string
+
+const (
+	SHA256 HashAlgorithm = "sha-256"
+	MD5    HashAlgorithm = "md5"
+	CRC32C HashAlgorithm = "crc32c"
+)
+
+var (
+	ErrChecksumMismatch     = errors.New("checksum mismatch")
+	ErrInvalidDigest        = errors.New("invalid digest")
+	ErrUnsupportedAlgorithm = errors.New("unsupported hash algorithm")
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumMismatchError is returned when a computed digest differs from the one sent by the client.
+type ChecksumMismatchError struct {
+	Filename  string
+	Algorithm HashAlgorithm
+	Expected  []byte
+	Actual    []byte
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s checksum mismatch: expected %x, got %x", e.Filename, e.Algorithm, e.Expected, e.Actual)
+}
+
+func (e *ChecksumMismatchError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// Digest is an expected checksum for a file.
+type Digest struct {
+	Algorithm HashAlgorithm
+	Sum       []byte
+}
+
+// FileResult describes a file part once it has been read, with the checksums computed on the way.
+type FileResult struct {
+	Filename string
+	Path     string
+	Size     int64
+	SHA256   []byte
+	MD5      []byte
+	CRC32C   []byte
+}
+
+// Sum returns the checksum computed for alg, or nil if it was not requested.
+func (r *FileResult) Sum(alg HashAlgorithm) []byte {
+	switch alg {
+	case SHA256:
+		return r.SHA256
+	case MD5:
+		return r.MD5
+	case CRC32C:
+		return r.CRC32C
+	default:
+		return nil
+	}
+}
+
+// Verify compares the computed checksums with the expected digests.
+func (r *FileResult) Verify(digests ...Digest) error {
+	for _, d := range digests {
+		actual := r.Sum(d.Algorithm)
+		if actual == nil {
+			return fmt.Errorf("%w: %s was not computed", ErrUnsupportedAlgorithm, d.Algorithm)
+		}
+
+		if !bytes.Equal(actual, d.Sum) {
+			return &ChecksumMismatchError{Filename: r.Filename, Algorithm: d.Algorithm, Expected: d.Sum, Actual: actual}
+		}
+	}
+
+	return nil
+}
+
+// ChecksumMultipartFile streams fh once and returns its size and checksums.
+// SHA-256 is always computed; extra algorithms can be requested.
+func ChecksumMultipartFile(fh *multipart.FileHeader, algs ...HashAlgorithm) (*FileResult, error) {
+	h, err := newMultiHasher(algs)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	n, err := copyZeroAlloc(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.result(fh.Filename, "", n), nil
+}
+
+// SaveMultipartFileWithChecksum saves fh under path like SaveMultipartFile, computing the
+// checksums while the file is written so the content is read only once.
+// SHA-256 is always computed; extra algorithms can be requested.
+func SaveMultipartFileWithChecksum(fh *multipart.FileHeader, path string, algs ...HashAlgorithm) (res *FileResult, err error) {
+	h, err := newMultiHasher(algs)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := f.Close()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	ff, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := ff.Close()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	n, err := copyZeroAlloc(io.MultiWriter(ff, h), f)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.result(fh.Filename, path, n), nil
+}
+
+// SaveMultipartFileVerified saves fh under path and verifies it against the digests sent by the
+// client, either in the part headers (Content-Digest, Content-MD5) or in formDigests (usually the
+// value of a companion form field). The file is removed when verification fails.
+func SaveMultipartFileVerified(fh *multipart.FileHeader, path string, formDigests ...string) (*FileResult, error) {
+	digests, err := ExpectedDigests(fh, formDigests...)
+	if err != nil {
+		return nil, err
+	}
+
+	algs := make([]HashAlgorithm, 0, len(digests))
+	for _, d := range digests {
+		algs = append(algs, d.Algorithm)
+	}
+
+	res, err := SaveMultipartFileWithChecksum(fh, path, algs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = res.Verify(digests...); err != nil {
+		_ = os.Remove(path)
+
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ExpectedDigests collects the digests announced for fh in its Content-Digest and Content-MD5
+// part headers, followed by the ones parsed from formDigests.
+func ExpectedDigests(fh *multipart.FileHeader, formDigests ...string) ([]Digest, error) {
+	digests, err := DigestsFromHeader(fh.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range formDigests {
+		if v == "" {
+			continue
+		}
+
+		d, err := ParseContentDigest(v)
+		if err != nil {
+			return nil, err
+		}
+
+		digests = append(digests, d...)
+	}
+
+	return digests, nil
+}
+
+// DigestsFromHeader parses the Content-Digest and Content-MD5 headers of a part.
+func DigestsFromHeader(header textproto.MIMEHeader) ([]Digest, error) {
+	var digests []Digest
+
+	for _, v := range header.Values("Content-Digest") {
+		d, err := ParseContentDigest(v)
+		if err != nil {
+			return nil, err
+		}
+
+		digests = append(digests, d...)
+	}
+
+	if v := header.Get("Content-MD5"); v != "" {
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(sum) != md5.Size {
+			return nil, fmt.Errorf("%w: Content-MD5 %q", ErrInvalidDigest, v)
+		}
+
+		digests = append(digests, Digest{Algorithm: MD5, Sum: sum})
+	}
+
+	return digests, nil
+}
+
+// ParseContentDigest parses a comma separated list of digests. Each entry is either in the
+// RFC 9530 form (sha-256=:base64:) or a hex form (sha256:hex or sha256=hex).
+// Entries with an unknown algorithm are ignored.
+func ParseContentDigest(v string) ([]Digest, error) {
+	var digests []Digest
+
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.HasPrefix(value, ":") != strings.HasSuffix(value, ":") {
+			name, value, ok = strings.Cut(entry, ":")
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDigest, entry)
+		}
+
+		alg, known := parseHashAlgorithm(name)
+		if !known {
+			continue
+		}
+
+		sum, err := decodeDigestValue(strings.TrimSpace(value))
+		if err != nil || len(sum) != digestSize(alg) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDigest, entry)
+		}
+
+		digests = append(digests, Digest{Algorithm: alg, Sum: sum})
+	}
+
+	return digests, nil
+}
+
+func decodeDigestValue(v string) ([]byte, error) {
+	if len(v) >= 2 && strings.HasPrefix(v, ":") && strings.HasSuffix(v, ":") {
+		return base64.StdEncoding.DecodeString(v[1 : len(v)-1])
+	}
+
+	return hex.DecodeString(v)
+}
+
+func parseHashAlgorithm(name string) (HashAlgorithm, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "sha-256", "sha256":
+		return SHA256, true
+	case "md5":
+		return MD5, true
+	case "crc32c":
+		return CRC32C, true
+	default:
+		return "", false
+	}
+}
+
+func digestSize(alg HashAlgorithm) int {
+	switch alg {
+	case SHA256:
+		return sha256.Size
+	case MD5:
+		return md5.Size
+	case CRC32C:
+		return crc32.Size
+	default:
+		return 0
+	}
+}
+
+// multiHasher feeds every write to a set of hashes.
+type multiHasher struct {
+	io.Writer
+
+	hashes map[HashAlgorithm]hash.Hash
+}
+
+func newMultiHasher(algs []HashAlgorithm) (*multiHasher, error) {
+	h := &multiHasher{hashes: map[HashAlgorithm]hash.Hash{SHA256: sha256.New()}}
+
+	for _, alg := range algs {
+		if _, ok := h.hashes[alg]; ok {
+			continue
+		}
+
+		switch alg {
+		case SHA256:
+		case MD5:
+			h.hashes[alg] = md5.New() //nolint:gosec
+		case CRC32C:
+			h.hashes[alg] = crc32.New(crc32cTable)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+		}
+	}
+
+	writers := make([]io.Writer, 0, len(h.hashes))
+	for _, hh := range h.hashes {
+		writers = append(writers, hh)
+	}
+
+	h.Writer = io.MultiWriter(writers...)
+
+	return h, nil
+}
+
+func (h *multiHasher) result(filename, path string, size int64) *FileResult {
+	res := &FileResult{Filename: filename, Path: path, Size: size}
+
+	for alg, hh := range h.hashes {
+		switch alg {
+		case SHA256:
+			res.SHA256 = hh.Sum(nil)
+		case MD5:
+			res.MD5 = hh.Sum(nil)
+		case CRC32C:
+			res.CRC32C = hh.Sum(nil)
+		}
+	}
+
+	return res
+}
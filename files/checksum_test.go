@@ -0,0 +1,79 @@
+package files
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileHeaderWithHeaders(t *testing.T, content string, headers map[string]string) *multipart.FileHeader {
+	t.Helper()
+
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", `form-data; name="file"; filename="data.bin"`)
+
+	for k, v := range headers {
+		partHeader.Set(k, v)
+	}
+
+	return filestest.NewMultipart(t).Part(partHeader, []byte(content)).FileHeader("file")
+}
+
+func TestSaveMultipartFileVerified(t *testing.T) {
+	content := "hello world"
+	sha := sha256.Sum256([]byte(content))
+	sum := md5.Sum([]byte(content)) //nolint:gosec
+
+	fh := newFileHeaderWithHeaders(t, content, map[string]string{
+		"Content-Digest": "sha-256=:" + base64.StdEncoding.EncodeToString(sha[:]) + ":",
+		"Content-MD5":    base64.StdEncoding.EncodeToString(sum[:]),
+	})
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+
+	res, err := SaveMultipartFileVerified(fh, path, "crc32c:"+hex.EncodeToString([]byte{0xc9, 0x94, 0x65, 0xaa}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), res.Size)
+	assert.Equal(t, sha[:], res.SHA256)
+	assert.Equal(t, sum[:], res.MD5)
+	assert.Len(t, res.CRC32C, 4)
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(saved))
+}
+
+func TestSaveMultipartFileVerifiedMismatch(t *testing.T) {
+	fh := newFileHeaderWithHeaders(t, "tampered", nil)
+	path := filepath.Join(t.TempDir(), "data.bin")
+
+	_, err := SaveMultipartFileVerified(fh, path, "sha256:"+hex.EncodeToString(make([]byte, sha256.Size)))
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	var mismatch *ChecksumMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, SHA256, mismatch.Algorithm)
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParseContentDigest(t *testing.T) {
+	digests, err := ParseContentDigest("sha-512=:AAAA:, sha-256=:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ":")
+	require.NoError(t, err)
+	require.Len(t, digests, 1)
+	assert.Equal(t, SHA256, digests[0].Algorithm)
+
+	_, err = ParseContentDigest("md5=:short:")
+	assert.ErrorIs(t, err, ErrInvalidDigest)
+}
@@ -0,0 +1,112 @@
+package files
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// converters holds the functions registered with RegisterConverter, by type.
+var converters sync.Map
+
+// RegisterConverter makes the form binder parse values of type T with fn, ahead of any other rule.
+// It is meant to be called from an init function.
+func RegisterConverter[T any](fn func(string) (T, error)) {
+	converters.Store(reflect.TypeOf((*T)(nil)).Elem(), func(s string) (reflect.Value, error) {
+		v, err := fn(s)
+		return reflect.ValueOf(&v).Elem(), err
+	})
+}
+
+// isTextType reports whether values of t are parsed from text by decodeText rather than by kind.
+func isTextType(t reflect.Type) bool {
+	if _, ok := converters.Load(t); ok {
+		return true
+	}
+
+	if t == timeType || t == durationType {
+		return true
+	}
+
+	ptr := reflect.PointerTo(t)
+
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(jsonUnmarshalerType)
+}
+
+// decodeText sets fieldVal from s when its type has a registered converter, is a time.Time or time.Duration,
+// or implements encoding.TextUnmarshaler or json.Unmarshaler. It reports whether it handled the type.
+func decodeText(fieldVal reflect.Value, s string, opts fieldOptions) (bool, error) {
+	t := fieldVal.Type()
+
+	if convert, ok := converters.Load(t); ok {
+		v, err := convert.(func(string) (reflect.Value, error))(s)
+		if err != nil {
+			return true, fmt.Errorf("failed to convert %s: %w", t, err)
+		}
+
+		fieldVal.Set(v)
+
+		return true, nil
+	}
+
+	switch t {
+	case timeType:
+		layout := opts.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+
+		parsed, err := time.Parse(layout, s)
+		if err != nil {
+			return true, fmt.Errorf("failed to parse time: %w", err)
+		}
+
+		fieldVal.Set(reflect.ValueOf(parsed))
+
+		return true, nil
+	case durationType:
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return true, fmt.Errorf("failed to parse duration: %w", err)
+		}
+
+		fieldVal.SetInt(int64(parsed))
+
+		return true, nil
+	}
+
+	if !fieldVal.CanAddr() {
+		return false, nil
+	}
+
+	switch target := fieldVal.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		if err := target.UnmarshalText([]byte(s)); err != nil {
+			return true, fmt.Errorf("failed to parse %s: %w", t, err)
+		}
+
+		return true, nil
+	case json.Unmarshaler:
+		// A bare string is not valid JSON; retry it quoted so types that unmarshal from strings work.
+		if err := target.UnmarshalJSON([]byte(s)); err != nil {
+			if errQuoted := target.UnmarshalJSON([]byte(strconv.Quote(s))); errQuoted != nil {
+				return true, fmt.Errorf("failed to parse %s: %w", t, err)
+			}
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,317 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/metadata"
+)
+
+// DownloadStatusKey is the header metadata key carrying the HTTP status of a download.
+// The gateway side (marshal.WithHTTPBodyDownloads) applies and strips it.
+const DownloadStatusKey = "x-download-status"
+
+// DownloadHeaders are the header metadata keys set by ServeContent that the gateway turns into HTTP headers.
+var DownloadHeaders = []string{"accept-ranges", "content-disposition", "content-range", "etag", "last-modified"}
+
+// HTTPBodySender is the part of a grpc.ServerStreamingServer[httpbody.HttpBody] used to stream a download.
+type HTTPBodySender interface {
+	Context() context.Context
+	SendHeader(metadata.MD) error
+	Send(*httpbody.HttpBody) error
+}
+
+// ObjectReader reads a byte range of a stored object, e.g. a bucket object opened with a range request.
+type ObjectReader interface {
+	NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// Content describes what ServeContent streams.
+type Content struct {
+	// Name is the filename announced in Content-Disposition.
+	Name string
+	// ContentType defaults to the type guessed from the Name extension, then application/octet-stream.
+	ContentType string
+	// Size is the total size in bytes.
+	Size int64
+	// ModTime sets Last-Modified when not zero.
+	ModTime time.Time
+	// ETag defaults to a weak tag built from Size and ModTime.
+	ETag string
+	// Inline asks the client to display the content rather than save it.
+	Inline bool
+	// ReaderAt or Object provides the bytes. ReaderAt wins when both are set.
+	ReaderAt io.ReaderAt
+	Object   ObjectReader
+}
+
+// ServeFile streams the file at path, see ServeContent.
+func ServeFile(stream HTTPBodySender, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	return ServeContent(stream, Content{
+		Name:     filepath.Base(path),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		ReaderAt: f,
+	})
+}
+
+// ServeContent streams content as HttpBody chunks. Like http.ServeContent it honors the
+// If-None-Match, If-Modified-Since, Range and If-Range request headers, answering 304, 206 or 416
+// when appropriate. Only single byte ranges are served; other Range values get the full content.
+func ServeContent(stream HTTPBodySender, content Content) error {
+	if content.ReaderAt == nil && content.Object == nil {
+		return errors.New("content has no reader")
+	}
+
+	ctx := stream.Context()
+	incoming, _ := metadata.FromIncomingContext(ctx)
+
+	contentType := content.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(content.Name))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	etag := content.ETag
+	if etag == "" && !content.ModTime.IsZero() {
+		etag = fmt.Sprintf(`W/"%x-%x"`, content.Size, content.ModTime.UnixNano())
+	}
+
+	header := metadata.Pairs("accept-ranges", "bytes")
+
+	if content.Name != "" {
+		disposition := "attachment"
+		if content.Inline {
+			disposition = "inline"
+		}
+
+		header.Set("content-disposition", mime.FormatMediaType(disposition, map[string]string{"filename": content.Name}))
+	}
+
+	if etag != "" {
+		header.Set("etag", etag)
+	}
+
+	if !content.ModTime.IsZero() {
+		header.Set("last-modified", content.ModTime.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(incoming, etag, content.ModTime) {
+		header.Set(DownloadStatusKey, strconv.Itoa(http.StatusNotModified))
+
+		return stream.SendHeader(header)
+	}
+
+	offset, length, status := int64(0), content.Size, http.StatusOK
+
+	if rangeHeader := incomingHeader(incoming, "range"); rangeHeader != "" && ifRangeMatches(incoming, etag, content.ModTime) {
+		start, end, ok, satisfiable := parseSingleRange(rangeHeader, content.Size)
+
+		switch {
+		case ok && !satisfiable:
+			header.Set("content-range", fmt.Sprintf("bytes */%d", content.Size))
+			header.Set(DownloadStatusKey, strconv.Itoa(http.StatusRequestedRangeNotSatisfiable))
+
+			return stream.SendHeader(header)
+		case ok:
+			offset, length, status = start, end-start+1, http.StatusPartialContent
+			header.Set("content-range", fmt.Sprintf("bytes %d-%d/%d", start, end, content.Size))
+		}
+	}
+
+	header.Set(DownloadStatusKey, strconv.Itoa(status))
+
+	if err := stream.SendHeader(header); err != nil {
+		return err
+	}
+
+	r, err := openContent(ctx, content, offset, length)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return sendChunks(stream, r, contentType)
+}
+
+func openContent(ctx context.Context, content Content, offset, length int64) (io.ReadCloser, error) {
+	if content.ReaderAt != nil {
+		return io.NopCloser(io.NewSectionReader(content.ReaderAt, offset, length)), nil
+	}
+
+	return content.Object.NewRangeReader(ctx, offset, length)
+}
+
+func sendChunks(stream HTTPBodySender, r io.Reader, contentType string) error {
+	buf := make([]byte, defaultBufSize)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if sendErr := stream.Send(&httpbody.HttpBody{ContentType: contentType, Data: buf[:n]}); sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// incomingHeader reads a request header forwarded by the gateway, or sent as is by a gRPC client.
+func incomingHeader(md metadata.MD, name string) string {
+	if v := md.Get(runtime.MetadataPrefix + name); len(v) > 0 {
+		return v[0]
+	}
+
+	if v := md.Get(name); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+func notModified(md metadata.MD, etag string, modTime time.Time) bool {
+	if inm := incomingHeader(md, "if-none-match"); inm != "" {
+		return etag != "" && etagListMatches(inm, etag, false)
+	}
+
+	if ims := incomingHeader(md, "if-modified-since"); ims != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(ims)
+
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// ifRangeMatches reports whether a Range header should be honored given the If-Range precondition.
+func ifRangeMatches(md metadata.MD, etag string, modTime time.Time) bool {
+	ir := incomingHeader(md, "if-range")
+	if ir == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return etag != "" && etagListMatches(ir, etag, true)
+	}
+
+	t, err := http.ParseTime(ir)
+
+	return err == nil && !modTime.IsZero() && modTime.UTC().Truncate(time.Second).Equal(t)
+}
+
+// etagListMatches compares etag with a comma separated list of tags (or "*").
+// Strong comparison never matches weak tags.
+func etagListMatches(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSingleRange parses a "bytes=start-end" header. ok is false when the header is ignored
+// (malformed or several ranges); satisfiable is false when the range lies outside the content.
+func parseSingleRange(header string, size int64) (start, end int64, ok, satisfiable bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, false
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, false
+	}
+
+	if first == "" {
+		// Suffix range: the last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false, false
+		}
+
+		if n == 0 || size == 0 {
+			return 0, 0, true, false
+		}
+
+		if n > size {
+			n = size
+		}
+
+		return size - n, size - 1, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, false
+	}
+
+	if start >= size {
+		return 0, 0, true, false
+	}
+
+	end = size - 1
+
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, false
+		}
+
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true, true
+}
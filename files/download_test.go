@@ -0,0 +1,83 @@
+package files
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeHTTPBodyStream struct {
+	ctx    context.Context
+	header metadata.MD
+	data   strings.Builder
+}
+
+func (s *fakeHTTPBodyStream) Context() context.Context { return s.ctx }
+
+func (s *fakeHTTPBodyStream) SendHeader(md metadata.MD) error {
+	s.header = md
+	return nil
+}
+
+func (s *fakeHTTPBodyStream) Send(body *httpbody.HttpBody) error {
+	s.data.Write(body.GetData())
+	return nil
+}
+
+func serveTestContent(t *testing.T, headers ...string) *fakeHTTPBodyStream {
+	t.Helper()
+
+	stream := &fakeHTTPBodyStream{
+		ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(headers...)),
+	}
+
+	err := ServeContent(stream, Content{
+		Name:     "report.txt",
+		Size:     10,
+		ModTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ETag:     `"v1"`,
+		ReaderAt: strings.NewReader("0123456789"),
+	})
+	require.NoError(t, err)
+
+	return stream
+}
+
+func TestServeContent(t *testing.T) {
+	stream := serveTestContent(t)
+	assert.Equal(t, []string{"200"}, stream.header.Get(DownloadStatusKey))
+	assert.Equal(t, []string{`attachment; filename=report.txt`}, stream.header.Get("content-disposition"))
+	assert.Equal(t, []string{"Mon, 01 Jan 2024 00:00:00 GMT"}, stream.header.Get("last-modified"))
+	assert.Equal(t, "0123456789", stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-range", "bytes=2-4")
+	assert.Equal(t, []string{"206"}, stream.header.Get(DownloadStatusKey))
+	assert.Equal(t, []string{"bytes 2-4/10"}, stream.header.Get("content-range"))
+	assert.Equal(t, "234", stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-range", "bytes=-3")
+	assert.Equal(t, "789", stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-range", "bytes=2-4", "grpcgateway-if-range", `"v0"`)
+	assert.Equal(t, []string{"200"}, stream.header.Get(DownloadStatusKey))
+	assert.Equal(t, "0123456789", stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-range", "bytes=20-")
+	assert.Equal(t, []string{"416"}, stream.header.Get(DownloadStatusKey))
+	assert.Equal(t, []string{"bytes */10"}, stream.header.Get("content-range"))
+	assert.Empty(t, stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-if-none-match", `W/"v1"`)
+	assert.Equal(t, []string{"304"}, stream.header.Get(DownloadStatusKey))
+	assert.Empty(t, stream.data.String())
+
+	stream = serveTestContent(t, "grpcgateway-if-modified-since", time.Now().UTC().Format(http.TimeFormat))
+	assert.Equal(t, []string{"304"}, stream.header.Get(DownloadStatusKey))
+}
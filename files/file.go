@@ -0,0 +1,163 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+)
+
+// ErrNoFile is returned by the methods of a File that was not bound to an uploaded file.
+var ErrNoFile = errors.New("no file")
+
+var (
+	fileType          = reflect.TypeOf(File{})
+	fileHeaderType    = reflect.TypeOf(multipart.FileHeader{})
+	fileHeaderPtrType = reflect.TypeOf(&multipart.FileHeader{})
+	bytesReaderType   = reflect.TypeOf((*bytes.Reader)(nil))
+)
+
+// Storage persists uploaded files, on disk or in an object store, and returns where the file was saved.
+type Storage interface {
+	Save(ctx context.Context, file File) (string, error)
+}
+
+// DirStorage saves files in the directory Root under their sanitized names (see SaveMultipartFileIn).
+type DirStorage struct {
+	Root      string
+	Collision CollisionPolicy
+}
+
+func (s DirStorage) Save(_ context.Context, file File) (string, error) {
+	if file.header == nil {
+		return "", ErrNoFile
+	}
+
+	return SaveMultipartFileIn(file.header, s.Root, s.Collision)
+}
+
+// File is an uploaded file bound by ParseMultipartForm, e.g. `form:"avatar"` on a File or []File field.
+type File struct {
+	header *multipart.FileHeader
+}
+
+// NewFile wraps a file header.
+func NewFile(fh *multipart.FileHeader) File {
+	return File{header: fh}
+}
+
+// Header returns the underlying file header, nil when no file was bound.
+func (f File) Header() *multipart.FileHeader {
+	return f.header
+}
+
+// Filename returns the filename sent by the client, unsanitized.
+func (f File) Filename() string {
+	if f.header == nil {
+		return ""
+	}
+
+	return f.header.Filename
+}
+
+// Size returns the size of the file in bytes, 0 when no file was bound.
+func (f File) Size() int64 {
+	if f.header == nil {
+		return 0
+	}
+
+	return f.header.Size
+}
+
+// Open opens the file for reading. The caller closes it.
+func (f File) Open() (multipart.File, error) {
+	if f.header == nil {
+		return nil, ErrNoFile
+	}
+
+	return f.header.Open()
+}
+
+// Bytes reads the whole file.
+func (f File) Bytes() ([]byte, error) {
+	if f.header == nil {
+		return nil, ErrNoFile
+	}
+
+	return readMultipartFile(f.header)
+}
+
+// ContentType sniffs the content type from the first bytes of the file, ignoring the type the client sent.
+func (f File) ContentType() (string, error) {
+	if f.header == nil {
+		return "", ErrNoFile
+	}
+
+	return sniffContentType(f.header)
+}
+
+// Hash computes the digest of the file with alg.
+func (f File) Hash(alg HashAlgorithm) ([]byte, error) {
+	if f.header == nil {
+		return nil, ErrNoFile
+	}
+
+	res, err := ChecksumMultipartFile(f.header, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Sum(alg), nil
+}
+
+// SaveTo persists the file in storage and returns where it was saved.
+func (f File) SaveTo(ctx context.Context, storage Storage) (string, error) {
+	if f.header == nil {
+		return "", ErrNoFile
+	}
+
+	return storage.Save(ctx, f)
+}
+
+// setFileValue binds an uploaded file into a File, a *multipart.FileHeader, a []byte or string holding
+// its content, or an io.Reader over its content. Nothing is left open, use File to stream large files.
+func setFileValue(fieldVal reflect.Value, fh *multipart.FileHeader) error {
+	t := fieldVal.Type()
+
+	switch {
+	case t == fileHeaderPtrType:
+		fieldVal.Set(reflect.ValueOf(fh))
+	case t == fileHeaderType:
+		fieldVal.Set(reflect.ValueOf(fh).Elem())
+	case t == fileType:
+		fieldVal.Set(reflect.ValueOf(NewFile(fh)))
+	case t.Kind() == reflect.Ptr:
+		if fieldVal.IsNil() {
+			fieldVal.Set(reflect.New(t.Elem()))
+		}
+
+		return setFileValue(fieldVal.Elem(), fh)
+	case t.Kind() == reflect.String, t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		content, err := readMultipartFile(fh)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		fieldVal.Set(reflect.ValueOf(content).Convert(t))
+	case t.Kind() == reflect.Interface && fileHeaderPtrType.Implements(t):
+		fieldVal.Set(reflect.ValueOf(fh))
+	case t.Kind() == reflect.Interface && bytesReaderType.Implements(t):
+		content, err := readMultipartFile(fh)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		fieldVal.Set(reflect.ValueOf(bytes.NewReader(content)))
+	default:
+		return errors.New("Unsupported file target " + t.String())
+	}
+
+	return nil
+}
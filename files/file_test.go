@@ -0,0 +1,114 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+)
+
+type Gallery struct {
+	Cover    File                  `form:"cover" validate:"required,mimetype=image/png"`
+	Photos   []File                `form:"photos" validate:"max=3"`
+	Raw      []byte                `form:"notes"`
+	Text     string                `form:"notes"`
+	Reader   io.Reader             `form:"notes"`
+	Header   *multipart.FileHeader `form:"notes"`
+	Ratings  []int                 `form:"ratings"`
+	Weights  []float64             `form:"weights"`
+	Optional *File                 `form:"optional"`
+}
+
+func newGalleryBody(t *testing.T) (context.Context, *httpbody.HttpBody) {
+	t.Helper()
+
+	return filestest.NewMultipart(t).
+		FileWithType("cover", "cover.png", "application/octet-stream", []byte("\x89PNG\r\n\x1a\nimage")).
+		FileWithType("photos", "a.jpg", "application/octet-stream", []byte("A")).
+		FileWithType("photos", "b.jpg", "application/octet-stream", []byte("B")).
+		FileWithType("notes", "notes.txt", "application/octet-stream", []byte("some notes")).
+		Fields("ratings", "4", "ratings", "5", "weights", "0.5", "weights", "1.25").
+		HTTPBody()
+}
+
+func TestParseMultipartFormFiles(t *testing.T) {
+	ctx, body := newGalleryBody(t)
+
+	result, err := ParseMultipartForm[Gallery](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cover.png", result.Cover.Filename())
+	contentType, err := result.Cover.ContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	require.Len(t, result.Photos, 2)
+	assert.Equal(t, "b.jpg", result.Photos[1].Filename())
+	assert.Equal(t, int64(1), result.Photos[1].Size())
+
+	content, err := result.Photos[0].Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("A"), content)
+
+	sum, err := result.Photos[0].Hash(SHA256)
+	require.NoError(t, err)
+	expected := sha256.Sum256([]byte("A"))
+	assert.Equal(t, expected[:], sum)
+
+	assert.Equal(t, []byte("some notes"), result.Raw)
+	assert.Equal(t, "some notes", result.Text)
+	assert.Equal(t, "notes.txt", result.Header.Filename)
+
+	require.NotNil(t, result.Reader)
+	assert.NotImplements(t, (*io.Closer)(nil), result.Reader, "no file is left open")
+	fromReader, err := io.ReadAll(result.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, "some notes", string(fromReader))
+
+	assert.Equal(t, []int{4, 5}, result.Ratings)
+	assert.Equal(t, []float64{0.5, 1.25}, result.Weights)
+	assert.Nil(t, result.Optional)
+
+	dir := t.TempDir()
+	path, err := result.Cover.SaveTo(context.Background(), DirStorage{Root: dir, Collision: CollisionSuffix})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "cover.png"), path)
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG\r\n\x1a\nimage", string(saved))
+
+	_, err = File{}.Bytes()
+	assert.ErrorIs(t, err, ErrNoFile)
+}
+
+func TestParseMultipartFormTextIntoInterfaces(t *testing.T) {
+	ctx, body := filestest.NewMultipart(t).Fields("notes", "typed notes", "label", "docs").HTTPBody()
+
+	type notes struct {
+		Reader io.Reader    `form:"notes"`
+		Label  fmt.Stringer `form:"label"`
+	}
+
+	result, err := ParseMultipartForm[notes](ctx, body)
+
+	var bindingErr *BindingError
+	require.ErrorAs(t, err, &bindingErr)
+	require.Len(t, bindingErr.Fields, 1)
+	assert.Equal(t, "label", bindingErr.Fields[0].Field)
+	assert.Equal(t, "fmt.Stringer", bindingErr.Fields[0].Type)
+
+	require.NotNil(t, result.Reader)
+	content, err := io.ReadAll(result.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, "typed notes", string(content))
+}
@@ -104,15 +104,23 @@ func SaveMultipartFile(fh *multipart.FileHeader, path string) (err error) {
 }
 
 type FormData struct {
-	form *multipart.Form
+	form  *multipart.Form
+	parts []*Part
 }
 
+// NewFormData parses a multipart body. multipart/form-data is read with ReadForm; other
+// multipart types (mixed, related) are read part by part, see Parts, and their named parts
+// are exposed as values and files too.
 func NewFormData(ctx context.Context, body *httpbody.HttpBody) (*FormData, error) {
-	boundary, err := extractBoundaryFromContext(ctx)
+	mediaType, boundary, err := extractMediaTypeFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if mediaType != "multipart/form-data" {
+		return newFormDataFromParts(body, boundary)
+	}
+
 	reader := multipart.NewReader(bytes.NewReader(body.GetData()), boundary)
 
 	form, err := reader.ReadForm(maxMemory)
@@ -123,6 +131,28 @@ func NewFormData(ctx context.Context, body *httpbody.HttpBody) (*FormData, error
 	return &FormData{form: form}, err
 }
 
+func newFormDataFromParts(body *httpbody.HttpBody, boundary string) (*FormData, error) {
+	budget := int64(maxMemory)
+
+	parts, err := readParts(multipart.NewReader(bytes.NewReader(body.GetData()), boundary), 0, &budget)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := partsToForm(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FormData{form: form, parts: parts}, nil
+}
+
+// Parts returns the parts of a non form-data multipart body, in order, with all their headers.
+// It is nil for multipart/form-data.
+func (f *FormData) Parts() []*Part {
+	return f.parts
+}
+
 func (f *FormData) Value(key string) []string {
 	return f.form.Value[key]
 }
@@ -145,22 +175,38 @@ func (f *FormData) RemoveAll() error {
 
 // extractBoundaryFromContext retrieves the boundary from the content-type metadata.
 func extractBoundaryFromContext(ctx context.Context) (string, error) {
+	_, boundary, err := extractMediaTypeFromContext(ctx)
+
+	return boundary, err
+}
+
+// extractMediaTypeFromContext retrieves the multipart media type and its boundary from the content-type metadata.
+func extractMediaTypeFromContext(ctx context.Context) (string, string, error) {
 	md, _ := metadata.FromIncomingContext(ctx)
 	contentType := md.Get(fmt.Sprintf("%s%s", runtime.MetadataPrefix, "content-type"))
 
 	if len(contentType) == 0 {
-		return "", http.ErrNotMultipart
+		return "", "", http.ErrNotMultipart
 	}
 
 	mediaType, params, err := mime.ParseMediaType(contentType[0])
-	if err != nil || !(mediaType == "multipart/form-data" || mediaType == "multipart/mixed") {
-		return "", http.ErrNotMultipart
+	if err != nil || !isSupportedMultipart(mediaType) {
+		return "", "", http.ErrNotMultipart
 	}
 
 	boundary, ok := params["boundary"]
 	if !ok {
-		return "", http.ErrMissingBoundary
+		return "", "", http.ErrMissingBoundary
 	}
 
-	return boundary, nil
+	return mediaType, boundary, nil
+}
+
+func isSupportedMultipart(mediaType string) bool {
+	switch mediaType {
+	case "multipart/form-data", "multipart/mixed", "multipart/related":
+		return true
+	default:
+		return false
+	}
 }
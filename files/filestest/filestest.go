@@ -0,0 +1,229 @@
+// Package filestest builds multipart requests for tests of handlers using the files package.
+package filestest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/metadata"
+)
+
+// Multipart builds a multipart/form-data body, part after part. Errors fail the test right away.
+type Multipart struct {
+	t         testing.TB
+	body      *bytes.Buffer
+	writer    *multipart.Writer
+	mediaType string
+	closed    bool
+}
+
+func NewMultipart(t testing.TB) *Multipart {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+
+	return &Multipart{t: t, body: body, writer: multipart.NewWriter(body), mediaType: "multipart/form-data"}
+}
+
+// Type replaces multipart/form-data, e.g. with multipart/mixed.
+func (m *Multipart) Type(mediaType string) *Multipart {
+	m.mediaType = mediaType
+
+	return m
+}
+
+// Boundary replaces the random boundary. It must come before the parts.
+func (m *Multipart) Boundary(boundary string) *Multipart {
+	m.t.Helper()
+
+	require.NoError(m.t, m.writer.SetBoundary(boundary))
+
+	return m
+}
+
+// Field adds a form field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.t.Helper()
+
+	require.NoError(m.t, m.writer.WriteField(name, value))
+
+	return m
+}
+
+// Fields adds form fields from key/value pairs, in order.
+func (m *Multipart) Fields(pairs ...string) *Multipart {
+	m.t.Helper()
+
+	require.Zero(m.t, len(pairs)%2, "Fields takes key/value pairs")
+
+	for i := 0; i < len(pairs); i += 2 {
+		m.Field(pairs[i], pairs[i+1])
+	}
+
+	return m
+}
+
+// JSON adds a form field holding v encoded as JSON, with an application/json part type.
+func (m *Multipart) JSON(name string, v any) *Multipart {
+	m.t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(m.t, err)
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": name}))
+	header.Set("Content-Type", "application/json")
+
+	return m.Part(header, data)
+}
+
+// File adds a file. Its part type is guessed from the filename extension, then from the content.
+func (m *Multipart) File(field, filename string, content []byte) *Multipart {
+	m.t.Helper()
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	return m.FileWithType(field, filename, contentType, content)
+}
+
+// FileWithType adds a file with the given part type.
+func (m *Multipart) FileWithType(field, filename, contentType string, content []byte) *Multipart {
+	m.t.Helper()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": filename}))
+	header.Set("Content-Type", contentType)
+
+	return m.Part(header, content)
+}
+
+// FileFromDisk adds the file at path, under its base name.
+func (m *Multipart) FileFromDisk(field, path string) *Multipart {
+	m.t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(m.t, err)
+
+	return m.File(field, filepath.Base(path), content)
+}
+
+// Part adds a part with custom headers, e.g. a Content-Transfer-Encoding or a nested multipart/mixed.
+func (m *Multipart) Part(header textproto.MIMEHeader, content []byte) *Multipart {
+	m.t.Helper()
+
+	w, err := m.writer.CreatePart(header)
+	require.NoError(m.t, err)
+
+	_, err = w.Write(content)
+	require.NoError(m.t, err)
+
+	return m
+}
+
+// ContentType is the multipart type of the body, with its boundary.
+func (m *Multipart) ContentType() string {
+	return mime.FormatMediaType(m.mediaType, map[string]string{"boundary": m.writer.Boundary()})
+}
+
+// Bytes ends the body and returns it. No part can be added afterwards.
+func (m *Multipart) Bytes() []byte {
+	m.t.Helper()
+
+	if !m.closed {
+		require.NoError(m.t, m.writer.Close())
+		m.closed = true
+	}
+
+	return m.body.Bytes()
+}
+
+// HTTPBody returns the body as a gRPC handler taking an HttpBody gets it through the gateway,
+// and an incoming context holding its content type.
+func (m *Multipart) HTTPBody() (context.Context, *httpbody.HttpBody) {
+	m.t.Helper()
+
+	data := m.Bytes()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(runtime.MetadataPrefix+"content-type", m.ContentType()))
+
+	return ctx, &httpbody.HttpBody{ContentType: m.ContentType(), Data: data}
+}
+
+// FileHeader returns the first file of field, as a handler parsing the form gets it. The temporary
+// files of the form are removed when the test ends.
+func (m *Multipart) FileHeader(field string) *multipart.FileHeader {
+	m.t.Helper()
+
+	form, err := multipart.NewReader(bytes.NewReader(m.Bytes()), m.writer.Boundary()).ReadForm(32 << 20)
+	require.NoError(m.t, err)
+
+	m.t.Cleanup(func() { _ = form.RemoveAll() })
+
+	require.NotEmpty(m.t, form.File[field], "no file %s", field)
+
+	return form.File[field][0]
+}
+
+// Request returns an HTTP request with the body, for gateway tests.
+func (m *Multipart) Request(method, target string) *http.Request {
+	m.t.Helper()
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(m.Bytes()))
+	req.Header.Set("Content-Type", m.ContentType())
+
+	return req
+}
+
+// AssertFile checks the name, and the content when not nil, of an uploaded file.
+func AssertFile(t testing.TB, fh *multipart.FileHeader, filename string, content []byte) bool {
+	t.Helper()
+
+	if !assert.NotNil(t, fh, "no file %s", filename) {
+		return false
+	}
+
+	ok := assert.Equal(t, filename, fh.Filename)
+
+	if content == nil {
+		return ok
+	}
+
+	f, err := fh.Open()
+	if !assert.NoError(t, err) {
+		return false
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	return assert.Equal(t, string(content), string(got), "content of %s", filename) && ok
+}
+
+// AssertFileType checks the part type of an uploaded file, ignoring its parameters.
+func AssertFileType(t testing.TB, fh *multipart.FileHeader, contentType string) bool {
+	t.Helper()
+
+	got, _, _ := strings.Cut(fh.Header.Get("Content-Type"), ";")
+
+	return assert.Equal(t, contentType, strings.TrimSpace(got))
+}
@@ -0,0 +1,60 @@
+package filestest_test
+
+import (
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files"
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Profile struct {
+	Name string `form:"name"`
+}
+
+type Upload struct {
+	Profile Profile                 `form:"profile"`
+	Tags    []string                `form:"tags"`
+	Avatar  *multipart.FileHeader   `form:"avatar"`
+	Docs    []*multipart.FileHeader `form:"docs"`
+}
+
+func TestMultipart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("from disk"), 0o600))
+
+	build := func() *filestest.Multipart {
+		return filestest.NewMultipart(t).
+			Boundary("test-boundary").
+			JSON("profile", Profile{Name: "Ada"}).
+			Fields("tags", "a", "tags", "b").
+			File("avatar", "avatar.png", []byte("\x89PNG\r\n\x1a\n")).
+			FileFromDisk("docs", path).
+			FileWithType("docs", "report", "application/pdf", []byte("%PDF-1.4"))
+	}
+
+	ctx, body := build().HTTPBody()
+	assert.Equal(t, "multipart/form-data; boundary=test-boundary", body.GetContentType())
+
+	upload, err := files.ParseMultipartForm[Upload](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Ada", upload.Profile.Name)
+	assert.Equal(t, []string{"a", "b"}, upload.Tags)
+	filestest.AssertFile(t, upload.Avatar, "avatar.png", nil)
+	filestest.AssertFileType(t, upload.Avatar, "image/png")
+	require.Len(t, upload.Docs, 2)
+	filestest.AssertFile(t, upload.Docs[0], "notes.txt", []byte("from disk"))
+	filestest.AssertFileType(t, upload.Docs[0], "text/plain")
+	filestest.AssertFile(t, upload.Docs[1], "report", []byte("%PDF-1.4"))
+
+	req := build().Request(http.MethodPost, "/uploads")
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	assert.Equal(t, []string{"a", "b"}, req.MultipartForm.Value["tags"])
+	assert.Len(t, req.MultipartForm.File["docs"], 2)
+}
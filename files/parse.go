@@ -1,18 +1,58 @@
 package files
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
 	"google.golang.org/genproto/googleapis/api/httpbody"
 )
 
-func ParseMultipartForm[T any](ctx context.Context, body *httpbody.HttpBody) (*T, error) {
+// ErrRequired is the cause of the FieldError of a missing field tagged required.
+var ErrRequired = errors.New("is required")
+
+// maxSliceIndex bounds the indexes accepted in keys like items[3] so a client can't make us allocate a huge slice.
+const maxSliceIndex = 10_000
+
+// ParseOption customizes how ParseMultipartForm binds a form.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	emptyAsAbsent bool
+}
+
+// WithEmptyAsAbsent treats keys sent with an empty value as missing, so defaults apply,
+// required fields fail and pointers stay nil.
+func WithEmptyAsAbsent() ParseOption {
+	return func(c *parseConfig) {
+		c.emptyAsAbsent = true
+	}
+}
+
+// ParseMultipartForm binds a multipart body into a new T, then checks its `validate` tags (see Validate).
+// When fields can't be bound, the other fields are still checked and the error is a *ValidationError
+// holding the *BindingError.
+//
+// Besides its key, a form tag accepts the options required (the key must be sent), default=value
+// (used when the key is missing) and layout (see time.Time fields), e.g. `form:"page,default=1"`.
+// A layout may contain commas, `form:"slot,layout=Mon, 02 Jan 2006,required"`: it ends before the
+// next comma followed by another option.
+// Pointer fields stay nil only when their key is missing.
+func ParseMultipartForm[T any](ctx context.Context, body *httpbody.HttpBody, opts ...ParseOption) (*T, error) {
 	dst := new(T)
 
+	cfg := parseConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	formData, err := NewFormData(ctx, body)
 	if err != nil {
 		return dst, err
@@ -36,61 +76,489 @@ func ParseMultipartForm[T any](ctx context.Context, body *httpbody.HttpBody) (*T
 		}
 	}
 
-	err = mapToStruct(data, dst)
-	if err != nil {
+	var bindingErr *BindingError
+
+	err = mapToStruct(data, dst, cfg)
+	if err != nil && !errors.As(err, &bindingErr) {
 		return dst, err
 	}
 
-	return dst, nil
+	err = Validate(dst)
+	if bindingErr == nil {
+		return dst, err
+	}
+
+	// The fields that were bound are still checked, so the client gets every problem at once.
+	combined := &ValidationError{Binding: bindingErr}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		for _, v := range validationErr.Violations {
+			if !bindingErr.failed(v.Field) {
+				combined.Violations = append(combined.Violations, v)
+			}
+		}
+	}
+
+	return dst, combined
 }
 
-func mapToStruct(data map[string][]any, dst any) (err error) {
-	dstVal := reflect.ValueOf(dst).Elem()
+// formNode is a form key split on its dots and brackets: address.city, items[0].name,
+// tags[] and labels[env] all become paths in a tree of nodes.
+type formNode struct {
+	values   []any
+	children map[string]*formNode
+}
 
-	if dstVal.Kind() == reflect.Struct {
-		for i := 0; i < dstVal.NumField(); i++ {
-			field := dstVal.Type().Field(i)
-			fieldVal := dstVal.Field(i)
-			key := field.Tag.Get("form")
+func newFormTree(data map[string][]any) *formNode {
+	root := &formNode{}
 
-			if key == "" {
-				key = field.Name
-			}
+	for key, values := range data {
+		node := root
+
+		for _, segment := range splitFormKey(key) {
+			node = node.child(segment)
+		}
+
+		node.values = append(node.values, values...)
+	}
 
-			if value, exists := data[key]; exists {
-				var valueType reflect.Type
-				if len(value) > 0 {
-					valueType = reflect.TypeOf(value[0])
-				}
-
-				if field.Type.Kind() == reflect.Ptr || field.Type.Kind() == reflect.Pointer { //nolint:gocritic
-					if field.Type.Elem().Kind() != reflect.Slice && field.Type.Elem().Kind() != reflect.Array {
-						err = setFieldValue(fieldVal, value[0])
-					}
-				} else if field.Type.Kind() != reflect.Slice && field.Type.Kind() != reflect.Array {
-					err = setFieldValue(fieldVal, value[0])
-				} else {
-					err = setFieldValue(fieldVal, value, valueType)
-				}
-
-				if err != nil {
-					return err
-				}
+	return root
+}
+
+func (n *formNode) child(name string) *formNode {
+	if n.children == nil {
+		n.children = make(map[string]*formNode)
+	}
+
+	c, ok := n.children[name]
+	if !ok {
+		c = &formNode{}
+		n.children[name] = c
+	}
+
+	return c
+}
+
+// lookup returns the node of a form key, which may itself be dotted, or nil.
+func (n *formNode) lookup(key string) *formNode {
+	node := n
+
+	for _, segment := range splitFormKey(key) {
+		if node = node.children[segment]; node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
+
+// splitFormKey splits items[0].name into items, 0, name. An empty bracket (tags[]) adds nothing:
+// repeated tags[] keys simply accumulate values.
+func splitFormKey(key string) []string {
+	var segments []string
+
+	for _, dotted := range strings.Split(key, ".") {
+		name, rest, _ := strings.Cut(dotted, "[")
+		if name != "" {
+			segments = append(segments, name)
+		}
+
+		for rest != "" {
+			var index string
+
+			index, rest, _ = strings.Cut(rest, "]")
+			if index != "" {
+				segments = append(segments, index)
 			}
+
+			rest = strings.TrimPrefix(rest, "[")
 		}
 	}
 
+	if len(segments) == 0 {
+		return []string{key}
+	}
+
+	return segments
+}
+
+// formFieldName returns the form key of a struct field, "-" when the field is skipped.
+func formFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	return name
+}
+
+// fieldOptions are the options following the name in a form tag, e.g. `form:"page,default=1"`.
+type fieldOptions struct {
+	required   bool
+	hasDefault bool
+	defaultVal string
+	// layout parses time.Time fields, time.RFC3339 by default.
+	layout string
+}
+
+func parseFieldOptions(tag string) fieldOptions {
+	var opts fieldOptions
+
+	_, rest, _ := strings.Cut(tag, ",")
+	for rest != "" {
+		var option string
+
+		option, rest = cutOption(rest)
+
+		switch name, value, _ := strings.Cut(option, "="); name {
+		case "required":
+			opts.required = true
+		case "default":
+			opts.hasDefault, opts.defaultVal = true, value
+		case "layout":
+			opts.layout = value
+		}
+	}
+
+	return opts
+}
+
+// cutOption cuts the first option of the options of a tag. A layout, which may contain commas,
+// runs until a comma followed by another option.
+func cutOption(options string) (string, string) {
+	if !strings.HasPrefix(options, "layout=") {
+		option, rest, _ := strings.Cut(options, ",")
+		return option, rest
+	}
+
+	for i := range len(options) {
+		if options[i] == ',' && isTagOption(options[i+1:]) {
+			return options[:i], options[i+1:]
+		}
+	}
+
+	return options, ""
+}
+
+func isTagOption(options string) bool {
+	option, _, _ := strings.Cut(options, ",")
+	name, _, _ := strings.Cut(option, "=")
+
+	return name == "required" || name == "default" || name == "layout"
+}
+
+func mapToStruct(data map[string][]any, dst any, cfg parseConfig) error {
+	dstVal := reflect.ValueOf(dst).Elem()
+	if dstVal.Kind() != reflect.Struct {
+		return nil
+	}
+
+	b := &binder{cfg: cfg}
+	b.bindStruct(newFormTree(data), dstVal, "")
+
+	if len(b.errs) > 0 {
+		return &BindingError{Fields: b.errs}
+	}
+
 	return nil
 }
 
-func setFieldValue(fieldVal reflect.Value, valueStr any, dataValType ...reflect.Type) error {
+// binder binds a form tree into a value, collecting the errors of every field instead of stopping at the first one.
+type binder struct {
+	cfg  parseConfig
+	errs []FieldError
+}
+
+func (b *binder) fail(node *formNode, fieldVal reflect.Value, path string, err error) {
+	b.errs = append(b.errs, newFieldError(node, fieldVal.Type(), path, err))
+}
+
+// bindStruct binds the children of node into the fields of the struct v, descending into
+// embedded structs whose fields are promoted to the same level. It reports whether a field was set.
+func (b *binder) bindStruct(node *formNode, v reflect.Value, prefix string) bool {
+	var bound bool
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		fieldVal := v.Field(i)
+
+		if field.Anonymous && field.Tag.Get("form") == "" {
+			bound = b.bindEmbedded(node, fieldVal, prefix) || bound
+			continue
+		}
+
+		key := formFieldName(field)
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		opts := parseFieldOptions(field.Tag.Get("form"))
+
+		child := b.present(node.lookup(key))
+		if child == nil {
+			b.bindMissing(fieldVal, path, opts)
+			continue
+		}
+
+		b.bindField(child, fieldVal, path, opts)
+
+		bound = true
+	}
+
+	return bound
+}
+
+// present returns node, or nil when it is missing or, with WithEmptyAsAbsent, only holds empty strings.
+func (b *binder) present(node *formNode) *formNode {
+	if node == nil || !b.cfg.emptyAsAbsent {
+		return node
+	}
+
+	values := make([]any, 0, len(node.values))
+
+	for _, v := range node.values {
+		if s, ok := v.(string); !ok || s != "" {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 && len(node.children) == 0 {
+		return nil
+	}
+
+	return &formNode{values: values, children: node.children}
+}
+
+// bindMissing applies the required and default options of a field whose key was not sent,
+// and descends into nested structs so their own defaults apply.
+func (b *binder) bindMissing(fieldVal reflect.Value, path string, opts fieldOptions) {
+	switch {
+	case opts.required:
+		b.fail(&formNode{}, fieldVal, path, ErrRequired)
+	case opts.hasDefault:
+		b.bindField(&formNode{values: []any{opts.defaultVal}}, fieldVal, path, opts)
+	case fieldVal.Kind() == reflect.Struct && !isTextType(fieldVal.Type()):
+		b.bindStruct(&formNode{}, fieldVal, path)
+	}
+}
+
+// bindEmbedded binds the promoted fields of an embedded struct, allocating it only when one of its fields is set.
+func (b *binder) bindEmbedded(node *formNode, fieldVal reflect.Value, prefix string) bool {
+	switch {
+	case fieldVal.Kind() == reflect.Struct:
+		return b.bindStruct(node, fieldVal, prefix)
+	case fieldVal.Kind() == reflect.Ptr && fieldVal.Type().Elem().Kind() == reflect.Struct && fieldVal.CanSet():
+		target := fieldVal
+		if fieldVal.IsNil() {
+			target = reflect.New(fieldVal.Type().Elem())
+		}
+
+		bound := b.bindStruct(node, target.Elem(), prefix)
+		if bound && fieldVal.IsNil() {
+			fieldVal.Set(target)
+		}
+
+		return bound
+	default:
+		return false
+	}
+}
+
+// bindField sets fieldVal from the values of node, then from its nested keys.
+func (b *binder) bindField(node *formNode, fieldVal reflect.Value, path string, opts fieldOptions) {
+	if len(node.values) > 0 {
+		if err := setValues(fieldVal, node.values, opts); err != nil {
+			b.fail(node, fieldVal, path, err)
+		}
+	}
+
+	if len(node.children) > 0 {
+		b.bindNested(node, fieldVal, path, opts)
+	}
+}
+
+func setValues(fieldVal reflect.Value, value []any, opts fieldOptions) error {
+	kind := fieldVal.Kind()
+
+	switch {
+	case kind == reflect.Ptr:
+		if fieldVal.Type().Elem().Kind() != reflect.Slice && fieldVal.Type().Elem().Kind() != reflect.Array {
+			return setFieldValue(fieldVal, value[0], opts)
+		}
+
+		return nil
+	case kind != reflect.Slice && kind != reflect.Array, fieldVal.Type().Elem().Kind() == reflect.Uint8, isTextType(fieldVal.Type()):
+		return setFieldValue(fieldVal, value[0], opts)
+	default:
+		return setSlice(fieldVal, value, opts)
+	}
+}
+
+// setSlice binds one element per value, each converted like a single field: []int from repeated
+// text values, []File from several files.
+func setSlice(fieldVal reflect.Value, values []any, opts fieldOptions) error {
+	target := fieldVal
+
+	if fieldVal.Kind() == reflect.Array {
+		if len(values) > fieldVal.Len() {
+			return fmt.Errorf("%d values for array of length %d", len(values), fieldVal.Len())
+		}
+	} else {
+		target = reflect.MakeSlice(fieldVal.Type(), len(values), len(values))
+	}
+
+	for i, v := range values {
+		if err := setFieldValue(target.Index(i), v, opts); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+
+	if fieldVal.Kind() == reflect.Slice {
+		fieldVal.Set(target)
+	}
+
+	return nil
+}
+
+// bindNested binds keys such as address.city, items[0].name or labels[env] into structs, slices and maps.
+func (b *binder) bindNested(node *formNode, fieldVal reflect.Value, path string, opts fieldOptions) {
+	switch fieldVal.Kind() {
+	case reflect.Ptr:
+		if fieldVal.IsNil() {
+			fieldVal.Set(reflect.New(fieldVal.Type().Elem()))
+		}
+
+		b.bindNested(node, fieldVal.Elem(), path, opts)
+	case reflect.Struct:
+		b.bindStruct(node, fieldVal, path)
+	case reflect.Slice, reflect.Array:
+		b.bindIndexed(node, fieldVal, path, opts)
+	case reflect.Map:
+		b.bindMap(node, fieldVal, path, opts)
+	case reflect.Interface:
+		if fieldVal.NumMethod() == 0 {
+			fieldVal.Set(reflect.ValueOf(nodeToAny(node)))
+			return
+		}
+
+		fallthrough
+	default:
+		b.fail(node, fieldVal, path, errors.New("Nested keys are not supported for kind "+fieldVal.Kind().String()))
+	}
+}
+
+func (b *binder) bindIndexed(node *formNode, fieldVal reflect.Value, path string, opts fieldOptions) {
+	indexes := make([]int, 0, len(node.children))
+	children := make(map[int]*formNode, len(node.children))
+
+	for _, key := range sortedKeys(node.children) {
+		child := node.children[key]
+
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > maxSliceIndex {
+			b.fail(child, fieldVal, fmt.Sprintf("%s[%s]", path, key), fmt.Errorf("invalid index %q", key))
+			continue
+		}
+
+		indexes = append(indexes, index)
+		children[index] = child
+	}
+
+	if len(indexes) == 0 {
+		return
+	}
+
+	sort.Ints(indexes)
+
+	size := indexes[len(indexes)-1] + 1
+
+	if fieldVal.Kind() == reflect.Array {
+		if size > fieldVal.Len() {
+			b.fail(node, fieldVal, path, fmt.Errorf("index %d out of range for array of length %d", size-1, fieldVal.Len()))
+			return
+		}
+	} else if size > fieldVal.Len() {
+		grown := reflect.MakeSlice(fieldVal.Type(), size, size)
+		reflect.Copy(grown, fieldVal)
+		fieldVal.Set(grown)
+	}
+
+	for _, index := range indexes {
+		b.bindField(children[index], fieldVal.Index(index), fmt.Sprintf("%s[%d]", path, index), opts)
+	}
+}
+
+func (b *binder) bindMap(node *formNode, fieldVal reflect.Value, path string, opts fieldOptions) {
+	mapType := fieldVal.Type()
+	if mapType.Key().Kind() != reflect.String {
+		b.fail(node, fieldVal, path, errors.New("Unsupported map key type "+mapType.Key().Kind().String()))
+		return
+	}
+
+	if fieldVal.IsNil() {
+		fieldVal.Set(reflect.MakeMap(mapType))
+	}
+
+	for _, key := range sortedKeys(node.children) {
+		elem := reflect.New(mapType.Elem()).Elem()
+		if existing := fieldVal.MapIndex(reflect.ValueOf(key).Convert(mapType.Key())); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		b.bindField(node.children[key], elem, fmt.Sprintf("%s[%s]", path, key), opts)
+
+		fieldVal.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), elem)
+	}
+}
+
+// nodeToAny turns a node into plain values for interface{} fields: a map of its children,
+// or its value (a slice when repeated).
+func nodeToAny(node *formNode) any {
+	if len(node.children) == 0 {
+		if len(node.values) == 1 {
+			return node.values[0]
+		}
+
+		return node.values
+	}
+
+	m := make(map[string]any, len(node.children))
+	for key, child := range node.children {
+		m[key] = nodeToAny(child)
+	}
+
+	return m
+}
+
+func setFieldValue(fieldVal reflect.Value, valueStr any, opts fieldOptions) error {
+	if fh, ok := valueStr.(*multipart.FileHeader); ok {
+		return setFileValue(fieldVal, fh)
+	}
+
+	if str, ok := valueStr.(string); ok && fieldVal.Kind() != reflect.Ptr {
+		if handled, err := decodeText(fieldVal, str, opts); handled {
+			return err
+		}
+	}
+
 	switch fieldVal.Kind() {
 	case reflect.Ptr:
 		if fieldVal.IsNil() {
 			fieldVal.Set(reflect.New(fieldVal.Type().Elem()))
 		}
 
-		return setFieldValue(fieldVal.Elem(), valueStr)
+		// A key sent empty still sets the pointer, to the zero value.
+		if str, ok := valueStr.(string); ok && str == "" {
+			return nil
+		}
+
+		return setFieldValue(fieldVal.Elem(), valueStr, opts)
 
 	case reflect.Struct:
 		if str, ok := valueStr.(string); ok {
@@ -125,25 +593,23 @@ func setFieldValue(fieldVal reflect.Value, valueStr any, dataValType ...reflect.
 		fieldVal.SetBool(boolValue)
 
 	case reflect.Slice, reflect.Array:
-		if fieldVal.Type().Elem().Kind() == reflect.Uint8 {
+		if fieldVal.Kind() == reflect.Slice && fieldVal.Type().Elem().Kind() == reflect.Uint8 {
 			fieldVal.SetBytes([]byte(valueStr.(string)))
 		} else {
-			// create a new slice of the same type as the data
-			if len(dataValType) == 0 {
-				return errors.New("Unsupported slice type " + fieldVal.Type().Elem().Kind().String())
-			}
-
-			sliceType := reflect.SliceOf(dataValType[0])
-			newSlice := reflect.MakeSlice(sliceType, 0, 0)
-
-			for _, v := range valueStr.([]any) {
-				newSlice = reflect.Append(newSlice, reflect.ValueOf(v))
-			}
-
-			fieldVal.Set(newSlice)
+			return setSlice(fieldVal, []any{valueStr}, opts)
 		}
 	case reflect.Interface:
-		fieldVal.Set(reflect.ValueOf(valueStr))
+		val := reflect.ValueOf(valueStr)
+
+		switch {
+		case val.Type().AssignableTo(fieldVal.Type()):
+			fieldVal.Set(val)
+		case val.Kind() == reflect.String && bytesReaderType.Implements(fieldVal.Type()):
+			// A text part sent for an io.Reader is read like the content of a file.
+			fieldVal.Set(reflect.ValueOf(bytes.NewReader([]byte(val.String()))))
+		default:
+			return errors.New("Unsupported interface " + fieldVal.Type().String() + " for a text value")
+		}
 	case reflect.Map:
 		if fieldVal.Type().Key().Kind() == reflect.String {
 			if err := json.Unmarshal([]byte(valueStr.(string)), fieldVal.Addr().Interface()); err != nil {
@@ -166,7 +632,7 @@ func setFieldValue(fieldVal reflect.Value, valueStr any, dataValType ...reflect.
 func setIntValue(fieldVal reflect.Value, valueStr string) error {
 	intValue, err := strconv.ParseInt(valueStr, 10, fieldVal.Type().Bits())
 	if err != nil {
-		return errors.New("failed to parse int")
+		return fmt.Errorf("failed to parse int: %w", errors.Unwrap(err))
 	}
 
 	fieldVal.SetInt(intValue)
@@ -177,7 +643,7 @@ func setIntValue(fieldVal reflect.Value, valueStr string) error {
 func setUintValue(fieldVal reflect.Value, valueStr string) error {
 	uintValue, err := strconv.ParseUint(valueStr, 10, fieldVal.Type().Bits())
 	if err != nil {
-		return errors.New("failed to parse uint")
+		return fmt.Errorf("failed to parse uint: %w", errors.Unwrap(err))
 	}
 
 	fieldVal.SetUint(uintValue)
@@ -188,7 +654,7 @@ func setUintValue(fieldVal reflect.Value, valueStr string) error {
 func setFloatValue(fieldVal reflect.Value, valueStr string) error {
 	floatValue, err := strconv.ParseFloat(valueStr, fieldVal.Type().Bits())
 	if err != nil {
-		return errors.New("failed to parse float")
+		return fmt.Errorf("failed to parse float: %w", errors.Unwrap(err))
 	}
 
 	fieldVal.SetFloat(floatValue)
@@ -0,0 +1,274 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newFormBody builds a multipart/form-data body from key/value pairs, in order.
+func newFormBody(t *testing.T, pairs ...string) (context.Context, *httpbody.HttpBody) {
+	t.Helper()
+
+	return filestest.NewMultipart(t).Fields(pairs...).HTTPBody()
+}
+
+type Address struct {
+	City string `form:"city"`
+	Zip  string `form:"zip"`
+}
+
+type Audit struct {
+	CreatedBy string `form:"created_by"`
+}
+
+type Item struct {
+	Name string `form:"name"`
+	Qty  int    `form:"qty"`
+}
+
+type Order struct {
+	Audit
+	*Address `form:"-"`
+
+	Customer string            `form:"customer"`
+	Shipping Address           `form:"address"`
+	Billing  *Address          `form:"billing"`
+	Items    []Item            `form:"items"`
+	Tags     []string          `form:"tags"`
+	Labels   map[string]string `form:"labels"`
+	Scores   map[string]Item   `form:"scores"`
+}
+
+func TestParseMultipartFormNested(t *testing.T) {
+	ctx, body := newFormBody(t,
+		"customer", "ACME",
+		"created_by", "jane",
+		"address.city", "Paris",
+		"address[zip]", "75001",
+		"billing.city", "Lyon",
+		"items[1].name", "bolt",
+		"items[1].qty", "4",
+		"items[0].name", "nut",
+		"tags[]", "a",
+		"tags[]", "b",
+		"labels[env]", "prod",
+		"scores.best.qty", "9",
+	)
+
+	result, err := ParseMultipartForm[Order](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ACME", result.Customer)
+	assert.Equal(t, "jane", result.CreatedBy)
+	assert.Equal(t, Address{City: "Paris", Zip: "75001"}, result.Shipping)
+	require.NotNil(t, result.Billing)
+	assert.Equal(t, "Lyon", result.Billing.City)
+	assert.Equal(t, []Item{{Name: "nut"}, {Name: "bolt", Qty: 4}}, result.Items)
+	assert.Equal(t, []string{"a", "b"}, result.Tags)
+	assert.Equal(t, map[string]string{"env": "prod"}, result.Labels)
+	assert.Equal(t, 9, result.Scores["best"].Qty)
+	assert.Nil(t, result.Address)
+
+	ctx, body = newFormBody(t, "items[100000].name", "x")
+	_, err = ParseMultipartForm[Order](ctx, body)
+	assert.Error(t, err)
+}
+
+func TestSplitFormKey(t *testing.T) {
+	assert.Equal(t, []string{"items", "0", "name"}, splitFormKey("items[0].name"))
+	assert.Equal(t, []string{"tags"}, splitFormKey("tags[]"))
+	assert.Equal(t, []string{"a", "b", "c"}, splitFormKey("a[b][c]"))
+	assert.Equal(t, []string{"address", "city"}, splitFormKey("address.city"))
+}
+
+type Cents int64
+
+type Level int
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	switch name {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level " + name)
+	}
+
+	return nil
+}
+
+type Booking struct {
+	Date     time.Time     `form:"date,layout=2006-01-02"`
+	Slot     time.Time     `form:"slot,layout=Mon, 02 Jan 2006 15:04,required"`
+	At       time.Time     `form:"at"`
+	Holidays []time.Time   `form:"holidays,layout=2006-01-02"`
+	Timeout  time.Duration `form:"timeout"`
+	Amount   *big.Int      `form:"amount"`
+	Server   net.IP        `form:"server"`
+	Price    Cents         `form:"price"`
+	Level    Level         `form:"level"`
+}
+
+func TestParseMultipartFormTextTypes(t *testing.T) {
+	RegisterConverter(func(s string) (Cents, error) {
+		units, cents, _ := strings.Cut(s, ".")
+
+		n, err := strconv.ParseInt(units+cents, 10, 64)
+
+		return Cents(n), err
+	})
+	t.Cleanup(func() { converters.Delete(reflect.TypeOf(Cents(0))) })
+
+	ctx, body := newFormBody(t,
+		"date", "2024-03-01",
+		"slot", "Fri, 01 Mar 2024 14:30",
+		"at", "2024-03-01T10:00:00Z",
+		"holidays", "2024-12-25",
+		"holidays", "2024-12-26",
+		"timeout", "1m30s",
+		"amount", "123456789012345678901234567890",
+		"server", "10.0.0.1",
+		"price", "12.34",
+		"level", "high",
+	)
+
+	result, err := ParseMultipartForm[Booking](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), result.Date)
+	assert.Equal(t, time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), result.Slot)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), result.At)
+	assert.Equal(t, []time.Time{time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)}, result.Holidays)
+	assert.Equal(t, 90*time.Second, result.Timeout)
+	assert.Equal(t, "123456789012345678901234567890", result.Amount.String())
+	assert.Equal(t, "10.0.0.1", result.Server.String())
+	assert.Equal(t, Cents(1234), result.Price)
+	assert.Equal(t, Level(2), result.Level)
+
+	ctx, body = newFormBody(t, "date", "01/03/2024")
+	_, err = ParseMultipartForm[Booking](ctx, body)
+	assert.Error(t, err)
+}
+
+func TestParseFieldOptions(t *testing.T) {
+	tests := map[string]fieldOptions{
+		"slot,layout=Mon, 02 Jan 2006":          {layout: "Mon, 02 Jan 2006"},
+		"slot,layout=Mon, 02 Jan 2006,required": {layout: "Mon, 02 Jan 2006", required: true},
+		"slot,default=now,layout=Mon, 02 Jan":   {layout: "Mon, 02 Jan", hasDefault: true, defaultVal: "now"},
+		"page,required,default=1":               {required: true, hasDefault: true, defaultVal: "1"},
+	}
+
+	for tag, want := range tests {
+		assert.Equal(t, want, parseFieldOptions(tag), tag)
+	}
+}
+
+func TestParseMultipartFormBindingError(t *testing.T) {
+	ctx, body := newFormBody(t,
+		"customer", "ACME",
+		"items[0].qty", "four",
+		"items[1].qty", "99999999999999999999",
+		"items[x].name", "bolt",
+		"address.city", "Paris",
+		"billing.zip", "1",
+		"created_by", strings.Repeat("x", 100),
+	)
+
+	type strictOrder struct {
+		Order
+
+		Count int `form:"created_by"`
+	}
+
+	result, err := ParseMultipartForm[strictOrder](ctx, body)
+
+	var bindingErr *BindingError
+	require.ErrorAs(t, err, &bindingErr)
+	require.Len(t, bindingErr.Fields, 4)
+
+	assert.Equal(t, "items[x]", bindingErr.Fields[0].Field)
+	assert.Equal(t, "items[0].qty", bindingErr.Fields[1].Field)
+	assert.Equal(t, "int", bindingErr.Fields[1].Type)
+	assert.Equal(t, "four", bindingErr.Fields[1].Value)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Equal(t, "items[1].qty", bindingErr.Fields[2].Field)
+	assert.ErrorIs(t, bindingErr.Fields[2], strconv.ErrRange)
+	assert.Equal(t, "created_by", bindingErr.Fields[3].Field)
+	assert.Equal(t, strings.Repeat("x", 64)+"...", bindingErr.Fields[3].Value)
+
+	assert.Equal(t, "ACME", result.Customer)
+	assert.Equal(t, "Paris", result.Shipping.City)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	assert.Len(t, st.Details()[0].(*errdetails.BadRequest).GetFieldViolations(), 4)
+}
+
+type SearchQuery struct {
+	Query    string  `form:"q,required"`
+	Page     int     `form:"page,default=1"`
+	Sort     string  `form:"sort,default=name"`
+	Limit    *int    `form:"limit"`
+	Offset   *int    `form:"offset"`
+	MinPrice *string `form:"min_price"`
+	Filters  struct {
+		Status string `form:"status,default=active"`
+	} `form:"filters"`
+}
+
+func TestParseMultipartFormDefaults(t *testing.T) {
+	ctx, body := newFormBody(t, "q", "shoes", "sort", "", "limit", "")
+
+	result, err := ParseMultipartForm[SearchQuery](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "shoes", result.Query)
+	assert.Equal(t, 1, result.Page)
+	assert.Equal(t, "", result.Sort)
+	require.NotNil(t, result.Limit)
+	assert.Equal(t, 0, *result.Limit)
+	assert.Nil(t, result.Offset)
+	assert.Equal(t, "active", result.Filters.Status)
+
+	ctx, body = newFormBody(t, "q", "shoes", "sort", "", "limit", "", "min_price", "")
+
+	result, err = ParseMultipartForm[SearchQuery](ctx, body, WithEmptyAsAbsent())
+	require.NoError(t, err)
+
+	assert.Equal(t, "name", result.Sort)
+	assert.Nil(t, result.Limit)
+	assert.Nil(t, result.MinPrice)
+
+	ctx, body = newFormBody(t, "q", "", "page", "2")
+
+	_, err = ParseMultipartForm[SearchQuery](ctx, body, WithEmptyAsAbsent())
+
+	var bindingErr *BindingError
+	require.ErrorAs(t, err, &bindingErr)
+	require.Len(t, bindingErr.Fields, 1)
+	assert.Equal(t, "q", bindingErr.Fields[0].Field)
+	assert.ErrorIs(t, err, ErrRequired)
+}
@@ -0,0 +1,267 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+)
+
+const maxPartDepth = 8
+
+var ErrTooDeep = errors.New("multipart nesting too deep")
+
+// Part is a part of a multipart body. A part whose content type is itself multipart has its
+// children in Parts and no Body.
+type Part struct {
+	Header textproto.MIMEHeader
+	Body   []byte
+	Parts  []*Part
+}
+
+// ContentType returns the media type of the part, text/plain when unset.
+func (p *Part) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		return "text/plain"
+	}
+
+	return mediaType
+}
+
+// IsMultipart reports whether the part holds nested parts.
+func (p *Part) IsMultipart() bool {
+	return strings.HasPrefix(p.ContentType(), "multipart/")
+}
+
+// FormName returns the name parameter of the Content-Disposition header.
+func (p *Part) FormName() string {
+	_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+
+	return params["name"]
+}
+
+// FileName returns the filename parameter of the Content-Disposition header.
+func (p *Part) FileName() string {
+	_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+
+	return params["filename"]
+}
+
+// ContentID returns the Content-ID header without its angle brackets, as used by multipart/related.
+func (p *Part) ContentID() string {
+	return strings.Trim(p.Header.Get("Content-ID"), "<> ")
+}
+
+// Walk calls fn for p and every nested part, depth first.
+func (p *Part) Walk(fn func(*Part) error) error {
+	if err := fn(p); err != nil {
+		return err
+	}
+
+	for _, child := range p.Parts {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewParts reads every part of a multipart body (multipart/form-data, multipart/mixed,
+// multipart/related, ...) with its headers, descending into nested multipart parts.
+func NewParts(ctx context.Context, body *httpbody.HttpBody) ([]*Part, error) {
+	_, boundary, err := extractMediaTypeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := int64(maxMemory)
+
+	return readParts(multipart.NewReader(bytes.NewReader(body.GetData()), boundary), 0, &budget)
+}
+
+func readParts(reader *multipart.Reader, depth int, budget *int64) ([]*Part, error) {
+	if depth > maxPartDepth {
+		return nil, ErrTooDeep
+	}
+
+	var parts []*Part
+
+	for {
+		// NextRawPart keeps Content-Transfer-Encoding untouched, so it is decoded below the same
+		// way for every part.
+		p, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		part := &Part{Header: p.Header}
+
+		data, err := io.ReadAll(io.LimitReader(p, *budget+1))
+		if err != nil {
+			return nil, err
+		}
+
+		*budget -= int64(len(data))
+		if *budget < 0 {
+			return nil, ErrSizeLimitExceeded
+		}
+
+		if data, err = decodeTransferEncoding(p.Header.Get("Content-Transfer-Encoding"), data); err != nil {
+			return nil, err
+		}
+
+		if part.IsMultipart() {
+			_, params, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+
+			boundary := params["boundary"]
+			if boundary == "" {
+				return nil, fmt.Errorf("nested part %q: %w", part.FormName(), http.ErrMissingBoundary)
+			}
+
+			// The nested parts are counted on their own, not twice.
+			*budget += int64(len(data))
+
+			if part.Parts, err = readParts(multipart.NewReader(bytes.NewReader(data), boundary), depth+1, budget); err != nil {
+				return nil, err
+			}
+		} else {
+			part.Body = data
+		}
+
+		parts = append(parts, part)
+	}
+}
+
+// decodeTransferEncoding decodes the transfer encodings still found in multipart/mixed bodies.
+func decodeTransferEncoding(encoding string, data []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "7bit", "8bit", "binary":
+		return data, nil
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(bytes.NewReader(data)))
+	case "base64":
+		// The decoder ignores the line breaks of MIME encoded bodies.
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data)))
+	default:
+		return nil, fmt.Errorf("unsupported Content-Transfer-Encoding %q", encoding)
+	}
+}
+
+// partsToForm turns the parts of a multipart/mixed or multipart/related body into a form.
+// Parts are named after their Content-Disposition name, or their Content-ID; nested parts
+// without a name take their parent's name, which is how several files used to be sent under
+// one form field. Parts with a filename become files, the others values.
+func partsToForm(parts []*Part) (*multipart.Form, error) {
+	form := &multipart.Form{Value: map[string][]string{}, File: map[string][]*multipart.FileHeader{}}
+
+	var files []*Part
+
+	var add func(parts []*Part, inherited string)
+
+	add = func(parts []*Part, inherited string) {
+		for _, p := range parts {
+			name := p.FormName()
+			if name == "" {
+				name = p.ContentID()
+			}
+
+			if name == "" {
+				name = inherited
+			}
+
+			switch {
+			case p.IsMultipart():
+				add(p.Parts, name)
+			case name == "":
+				// Parts without a name can't be bound.
+			case p.FileName() != "":
+				files = append(files, &Part{Header: formFileHeader(p, name), Body: p.Body})
+			default:
+				form.Value[name] = append(form.Value[name], string(p.Body))
+			}
+		}
+	}
+
+	add(parts, "")
+
+	if len(files) == 0 {
+		return form, nil
+	}
+
+	fileForm, err := readFileParts(files)
+	if err != nil {
+		return nil, err
+	}
+
+	form.File = fileForm.File
+
+	return form, nil
+}
+
+// formFileHeader returns the header of p as a form-data file part named name.
+func formFileHeader(p *Part, name string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+
+	for k, v := range p.Header {
+		if k != "Content-Disposition" && k != "Content-Transfer-Encoding" {
+			header[k] = v
+		}
+	}
+
+	params := map[string]string{"name": name, "filename": p.FileName()}
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", params))
+
+	return header
+}
+
+// readFileParts builds the multipart.FileHeader of file parts. Their content is unexported, so only
+// multipart.Reader can build them: the parts are streamed to it as a form-data body.
+func readFileParts(parts []*Part) (*multipart.Form, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		for _, p := range parts {
+			w, err := writer.CreatePart(p.Header)
+			if err == nil {
+				_, err = w.Write(p.Body)
+			}
+
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+
+		_ = pw.CloseWithError(writer.Close())
+	}()
+
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(maxMemory)
+
+	// Unblocks the writer when ReadForm stopped early.
+	_ = pr.Close()
+
+	return form, err
+}
@@ -0,0 +1,88 @@
+package files
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/metadata"
+)
+
+func newMixedBody(t *testing.T) (context.Context, *httpbody.HttpBody) {
+	t.Helper()
+
+	nested := filestest.NewMultipart(t).Type("multipart/mixed")
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		nested.Part(textproto.MIMEHeader{
+			"Content-Disposition": {`attachment; filename="` + name + `"`},
+			"Content-Type":        {"text/plain"},
+		}, []byte("content of "+name))
+	}
+
+	return filestest.NewMultipart(t).Type("multipart/mixed").
+		Part(textproto.MIMEHeader{
+			"Content-Type": {"application/json"},
+			"Content-ID":   {"<metadata>"},
+		}, []byte(`{"name":"batch"}`)).
+		Part(textproto.MIMEHeader{
+			"Content-Disposition":       {`form-data; name="note"`},
+			"Content-Transfer-Encoding": {"base64"},
+		}, []byte("aGVsbG8=\r\n")).
+		Part(textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="attachments"`},
+			"Content-Type":        {nested.ContentType()},
+		}, nested.Bytes()).
+		HTTPBody()
+}
+
+func TestNewParts(t *testing.T) {
+	ctx, body := newMixedBody(t)
+
+	parts, err := NewParts(ctx, body)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+
+	assert.Equal(t, "application/json", parts[0].ContentType())
+	assert.Equal(t, "metadata", parts[0].ContentID())
+	assert.Equal(t, "hello", string(parts[1].Body))
+	assert.True(t, parts[2].IsMultipart())
+	require.Len(t, parts[2].Parts, 2)
+	assert.Equal(t, "b.txt", parts[2].Parts[1].FileName())
+	assert.Equal(t, "content of b.txt", string(parts[2].Parts[1].Body))
+
+	// No marshaler passes multipart/alternative bodies to the gRPC server.
+	ctx = metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(runtime.MetadataPrefix+"content-type", "multipart/alternative; boundary=x"))
+	_, err = NewParts(ctx, body)
+	assert.ErrorIs(t, err, http.ErrNotMultipart)
+}
+
+func TestParseMultipartFormMixed(t *testing.T) {
+	type batch struct {
+		Metadata    struct{ Name string }   `form:"metadata"`
+		Note        string                  `form:"note"`
+		Attachments []*multipart.FileHeader `form:"attachments"`
+	}
+
+	ctx, body := newMixedBody(t)
+
+	result, err := ParseMultipartForm[batch](ctx, body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "batch", result.Metadata.Name)
+	assert.Equal(t, "hello", result.Note)
+	require.Len(t, result.Attachments, 2)
+	assert.Equal(t, "a.txt", result.Attachments[0].Filename)
+
+	content, err := readMultipartFile(result.Attachments[1])
+	require.NoError(t, err)
+	assert.Equal(t, "content of b.txt", string(content))
+}
@@ -0,0 +1,420 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var ErrOneofConflict = errors.New("several fields of the same oneof are set")
+
+// ParseMultipartFormProto fills msg, usually a generated request message, from a multipart body.
+// See BindProto for the binding rules.
+func ParseMultipartFormProto(ctx context.Context, body *httpbody.HttpBody, msg proto.Message) error {
+	formData, err := NewFormData(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	return BindProto(msg, formData.form.Value, formData.form.File)
+}
+
+// BindProto fills msg from form values and files.
+//
+// Keys match fields by proto name or json_name; dotted keys (address.city) reach nested
+// messages and map entries (labels.env). Scalars are parsed from text, enums by name or
+// number, Timestamp as RFC 3339, Duration as a Go or protobuf duration, wrappers as their
+// value, and any other message from JSON. Repeated fields take every value of their key.
+// Files go into bytes or google.api.HttpBody fields. Unknown keys are ignored.
+func BindProto(msg proto.Message, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	m := msg.ProtoReflect()
+	oneofs := make(map[protoreflect.FullName]string)
+
+	for _, key := range sortedKeys(values) {
+		if err := bindProtoPath(m, key, strings.Split(key, "."), oneofs, values[key], nil); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range sortedKeys(files) {
+		if err := bindProtoPath(m, key, strings.Split(key, "."), oneofs, nil, files[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// bindProtoPath walks path down to the field it names and sets it from values or files.
+func bindProtoPath(
+	m protoreflect.Message,
+	key string,
+	path []string,
+	oneofs map[protoreflect.FullName]string,
+	values []string,
+	files []*multipart.FileHeader,
+) error {
+	fd := findProtoField(m.Descriptor(), path[0])
+	if fd == nil {
+		return nil
+	}
+
+	if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		if other, ok := oneofs[oneof.FullName()]; ok && other != string(fd.Name()) {
+			return fmt.Errorf("%s: %w (%s and %s)", key, ErrOneofConflict, other, fd.Name())
+		}
+
+		oneofs[oneof.FullName()] = string(fd.Name())
+	}
+
+	var err error
+
+	switch {
+	case len(path) == 1 && files != nil:
+		err = setProtoFiles(m, fd, files)
+	case len(path) == 1:
+		err = setProtoValues(m, fd, values)
+	case fd.IsMap() && files == nil:
+		err = setProtoMapEntry(m, fd, strings.Join(path[1:], "."), values)
+	case fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() && !isWellKnownLeaf(fd.Message()):
+		return bindProtoPath(m.Mutable(fd).Message(), key, path[1:], oneofs, values, files)
+	default:
+		err = fmt.Errorf("field %s has no sub-fields", fd.Name())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return nil
+}
+
+// setProtoMapEntry sets the entry mapKey of the map field fd.
+func setProtoMapEntry(m protoreflect.Message, fd protoreflect.FieldDescriptor, mapKey string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	k, err := parseProtoScalar(fd.MapKey(), mapKey)
+	if err != nil {
+		return fmt.Errorf("map key: %w", err)
+	}
+
+	entries := m.Mutable(fd).Map()
+	valueFd := fd.MapValue()
+
+	if valueFd.Kind() == protoreflect.MessageKind {
+		return setProtoMessage(entries.Mutable(k.MapKey()).Message(), values[0])
+	}
+
+	v, err := parseProtoScalar(valueFd, values[0])
+	if err != nil {
+		return err
+	}
+
+	entries.Set(k.MapKey(), v)
+
+	return nil
+}
+
+func findProtoField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+
+	return fields.ByTextName(name)
+}
+
+func setProtoValues(m protoreflect.Message, fd protoreflect.FieldDescriptor, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if fd.IsMap() {
+		// A whole map is sent as a JSON object.
+		return unmarshalProtoField(m, fd, values[0])
+	}
+
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+
+		for _, v := range values {
+			var (
+				val protoreflect.Value
+				err error
+			)
+
+			if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+				elem := list.NewElement()
+				err = setProtoMessage(elem.Message(), v)
+				val = elem
+			} else {
+				val, err = parseProtoScalar(fd, v)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			list.Append(val)
+		}
+
+		return nil
+	}
+
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return setProtoMessage(m.Mutable(fd).Message(), values[0])
+	}
+
+	val, err := parseProtoScalar(fd, values[0])
+	if err != nil {
+		return err
+	}
+
+	m.Set(fd, val)
+
+	return nil
+}
+
+// unmarshalProtoField fills fd from its JSON representation.
+func unmarshalProtoField(m protoreflect.Message, fd protoreflect.FieldDescriptor, value string) error {
+	raw, err := json.Marshal(map[string]json.RawMessage{fd.JSONName(): json.RawMessage(value)})
+	if err != nil {
+		return err
+	}
+
+	tmp := m.New()
+	if err = (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, tmp.Interface()); err != nil {
+		return err
+	}
+
+	m.Set(fd, tmp.Get(fd))
+
+	return nil
+}
+
+// setProtoMessage fills a message field from a single text value.
+func setProtoMessage(m protoreflect.Message, value string) error {
+	switch m.Descriptor().FullName() {
+	case "google.protobuf.Timestamp":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", value)
+		}
+
+		setSecondsNanos(m, t.Unix(), int32(t.Nanosecond()))
+
+		return nil
+	case "google.protobuf.Duration":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+
+		setSecondsNanos(m, int64(d/time.Second), int32(d%time.Second))
+
+		return nil
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		fd := m.Descriptor().Fields().ByNumber(1)
+
+		val, err := parseProtoScalar(fd, value)
+		if err != nil {
+			return err
+		}
+
+		m.Set(fd, val)
+
+		return nil
+	case "google.api.HttpBody":
+		fields := m.Descriptor().Fields()
+		m.Set(fields.ByName("content_type"), protoreflect.ValueOfString("text/plain"))
+		m.Set(fields.ByName("data"), protoreflect.ValueOfBytes([]byte(value)))
+
+		return nil
+	}
+
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal([]byte(value), m.Interface()); err != nil {
+		return fmt.Errorf("invalid %s: %w", m.Descriptor().FullName(), err)
+	}
+
+	return nil
+}
+
+// setSecondsNanos fills a Timestamp or Duration, generated or dynamic.
+func setSecondsNanos(m protoreflect.Message, seconds int64, nanos int32) {
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+	m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+}
+
+// isWellKnownLeaf reports whether a message is bound from a single value rather than through sub-fields.
+func isWellKnownLeaf(md protoreflect.MessageDescriptor) bool {
+	name := string(md.FullName())
+
+	return strings.HasPrefix(name, "google.protobuf.") || name == "google.api.HttpBody"
+}
+
+func parseProtoScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid bool %q", value)
+		}
+
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid int32 %q", value)
+		}
+
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid int64 %q", value)
+		}
+
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid uint32 %q", value)
+		}
+
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid uint64 %q", value)
+		}
+
+		return protoreflect.ValueOfUint64(n), nil
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid float %q", value)
+		}
+
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid double %q", value)
+		}
+
+		return protoreflect.ValueOfFloat64(f), nil
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(value)), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid %s %q", fd.Enum().Name(), value)
+		}
+
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		fallthrough
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+	}
+}
+
+func setProtoFiles(m protoreflect.Message, fd protoreflect.FieldDescriptor, files []*multipart.FileHeader) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	isHTTPBody := fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == "google.api.HttpBody"
+	if fd.Kind() != protoreflect.BytesKind && !isHTTPBody {
+		return fmt.Errorf("field %s can't hold a file, use bytes or google.api.HttpBody", fd.Name())
+	}
+
+	if !fd.IsList() {
+		files = files[:1]
+	}
+
+	for _, fh := range files {
+		data, err := readMultipartFile(fh)
+		if err != nil {
+			return err
+		}
+
+		val := protoreflect.ValueOfBytes(data)
+
+		if isHTTPBody {
+			contentType := fh.Header.Get("Content-Type")
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+
+			// The message is built from the field so generated and dynamic messages both work.
+			val = m.NewField(fd)
+			if fd.IsList() {
+				val = m.Mutable(fd).List().NewElement()
+			}
+
+			body := val.Message()
+			fields := body.Descriptor().Fields()
+			body.Set(fields.ByName("content_type"), protoreflect.ValueOfString(contentType))
+			body.Set(fields.ByName("data"), protoreflect.ValueOfBytes(data))
+		}
+
+		if fd.IsList() {
+			m.Mutable(fd).List().Append(val)
+		} else {
+			m.Set(fd, val)
+		}
+	}
+
+	return nil
+}
+
+func readMultipartFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
@@ -0,0 +1,168 @@
+package files
+
+import (
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func protoField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   typ.Enum(),
+		Label:  label.Enum(),
+	}
+
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+
+	return field
+}
+
+func repeated(field *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return field
+}
+
+// newBindRequest builds a dynamic message covering the field kinds BindProto supports.
+func newBindRequest(t *testing.T) *dynamicpb.Message {
+	t.Helper()
+
+	const (
+		tString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		tMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		tInt32   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		tInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		tEnum    = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		tBytes   = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	)
+
+	email := protoField("email", 8, tString, "")
+	email.OneofIndex = proto.Int32(0)
+	phone := protoField("phone", 9, tString, "")
+	phone.OneofIndex = proto.Int32(0)
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("files/bind_test.proto"),
+		Package: proto.String("files.test"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			"google/protobuf/timestamp.proto",
+			"google/protobuf/duration.proto",
+			"google/protobuf/wrappers.proto",
+			"google/api/httpbody.proto",
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Color"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("RED"), Number: proto.Int32(1)},
+				{Name: proto.String("BLUE"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("BindRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				protoField("display_name", 1, tString, ""),
+				protoField("age", 2, tInt32, ""),
+				protoField("color", 3, tEnum, ".files.test.Color"),
+				repeated(protoField("ids", 4, tInt64, "")),
+				protoField("created_at", 5, tMessage, ".google.protobuf.Timestamp"),
+				protoField("ttl", 6, tMessage, ".google.protobuf.Duration"),
+				protoField("nickname", 7, tMessage, ".google.protobuf.StringValue"),
+				email,
+				phone,
+				protoField("avatar", 10, tBytes, ""),
+				repeated(protoField("attachments", 11, tMessage, ".google.api.HttpBody")),
+				protoField("address", 12, tMessage, ".files.test.BindRequest.Address"),
+				repeated(protoField("labels", 13, tMessage, ".files.test.BindRequest.LabelsEntry")),
+			},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("target")}},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name:  proto.String("Address"),
+					Field: []*descriptorpb.FieldDescriptorProto{protoField("city", 1, tString, "")},
+				},
+				{
+					Name: proto.String("LabelsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						protoField("key", 1, tString, ""),
+						protoField("value", 2, tString, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+			},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return dynamicpb.NewMessage(fd.Messages().ByName("BindRequest"))
+}
+
+func TestBindProto(t *testing.T) {
+	msg := newBindRequest(t)
+
+	err := BindProto(msg, map[string][]string{
+		"displayName":  {"Jane"},
+		"age":          {"42"},
+		"color":        {"BLUE"},
+		"ids":          {"1", "2", "3"},
+		"created_at":   {"2024-01-02T03:04:05Z"},
+		"ttl":          {"1m30s"},
+		"nickname":     {"jd"},
+		"phone":        {"+33600000000"},
+		"address.city": {"Paris"},
+		"labels.env":   {"prod"},
+		"unknown":      {"ignored"},
+	}, map[string][]*multipart.FileHeader{
+		"avatar":      {newFileHeader(t, "avatar.png", "png-bytes")},
+		"attachments": {newFileHeader(t, "a.txt", "A"), newFileHeader(t, "b.txt", "B")},
+	})
+	require.NoError(t, err)
+
+	fields := msg.Descriptor().Fields()
+	get := func(name string) protoreflect.Value { return msg.Get(fields.ByName(protoreflect.Name(name))) }
+
+	assert.Equal(t, "Jane", get("display_name").String())
+	assert.Equal(t, int64(42), get("age").Int())
+	assert.Equal(t, protoreflect.EnumNumber(2), get("color").Enum())
+	assert.Equal(t, 3, get("ids").List().Len())
+	assert.Equal(t, int64(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix()), get("created_at").Message().Get(
+		get("created_at").Message().Descriptor().Fields().ByName("seconds")).Int())
+	assert.Equal(t, int64(90), get("ttl").Message().Get(get("ttl").Message().Descriptor().Fields().ByName("seconds")).Int())
+	assert.Equal(t, "+33600000000", get("phone").String())
+	assert.Equal(t, "Paris", get("address").Message().Get(get("address").Message().Descriptor().Fields().ByName("city")).String())
+	assert.Equal(t, "prod", get("labels").Map().Get(protoreflect.ValueOfString("env").MapKey()).String())
+	assert.Equal(t, []byte("png-bytes"), get("avatar").Bytes())
+
+	attachments := get("attachments").List()
+	require.Equal(t, 2, attachments.Len())
+
+	second := attachments.Get(1).Message()
+	assert.Equal(t, []byte("B"), second.Get(second.Descriptor().Fields().ByName("data")).Bytes())
+
+	err = BindProto(newBindRequest(t), map[string][]string{"email": {"a@b.c"}, "phone": {"1"}}, nil)
+	require.ErrorIs(t, err, ErrOneofConflict)
+
+	err = BindProto(newBindRequest(t), map[string][]string{"color": {"PURPLE"}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,201 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	maxFilenameBytes = 255
+	maxSuffixTries   = 1000
+	fallbackFilename = "file"
+)
+
+var (
+	ErrFileExists    = errors.New("file already exists")
+	ErrPathTraversal = errors.New("path escapes base directory")
+)
+
+// CollisionPolicy tells SaveMultipartFileIn what to do when the target file already exists.
+type CollisionPolicy int
+
+const (
+	// CollisionOverwrite replaces the existing file.
+	CollisionOverwrite CollisionPolicy = iota
+	// CollisionFail returns ErrFileExists.
+	CollisionFail
+	// CollisionSuffix appends " (1)", " (2)", ... to the base name until a free name is found.
+	CollisionSuffix
+)
+
+// reservedNames are device names Windows refuses to use as a filename, with or without extension.
+var reservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// SanitizeFilename turns a client supplied filename into a single, safe path element.
+//
+// Directory components (both / and \) are dropped, the name is NFC normalized, control, format
+// (bidi overrides, zero-width) and shell/Windows special characters are replaced, leading dots and
+// trailing dots or spaces are trimmed, reserved device names are prefixed and the result is cut to
+// 255 bytes while keeping the extension. It never returns an empty string.
+func SanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = norm.NFC.String(name)
+
+	var b strings.Builder
+
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			continue
+		case strings.ContainsRune(`<>:"|?*`, r):
+			b.WriteRune('_')
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	name = strings.TrimLeft(b.String(), ". ")
+	name = strings.TrimRight(name, ". ")
+
+	if name == "" {
+		return fallbackFilename
+	}
+
+	// Windows reserves the device names with any extension, CON.tar.gz as much as CON.
+	device, _, _ := strings.Cut(name, ".")
+	if _, ok := reservedNames[strings.ToUpper(strings.TrimRight(device, ". "))]; ok {
+		name = "_" + name
+	}
+
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	if len(ext) > maxFilenameBytes/2 {
+		ext = ""
+	}
+
+	return truncateUTF8(stem, maxFilenameBytes-len(ext)) + ext
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// SafeJoin joins root and name and makes sure the result stays inside root.
+func SafeJoin(root, name string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(root, name)
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrPathTraversal
+	}
+
+	return path, nil
+}
+
+// SaveMultipartFileIn saves fh inside the root directory under its sanitized filename and
+// returns the path that was written. The policy decides what happens when the file already exists.
+func SaveMultipartFileIn(fh *multipart.FileHeader, root string, policy CollisionPolicy) (string, error) {
+	name := SanitizeFilename(fh.Filename)
+
+	path, err := SafeJoin(root, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch policy {
+	case CollisionOverwrite:
+		return path, SaveMultipartFile(fh, path)
+	case CollisionFail:
+		return path, saveMultipartFileExclusive(fh, path)
+	case CollisionSuffix:
+		ext := filepath.Ext(name)
+		stem := strings.TrimSuffix(name, ext)
+
+		for i := 0; i < maxSuffixTries; i++ {
+			if i > 0 {
+				suffix := fmt.Sprintf(" (%d)", i)
+				path = filepath.Join(filepath.Dir(path), truncateUTF8(stem, maxFilenameBytes-len(ext)-len(suffix))+suffix+ext)
+			}
+
+			err = saveMultipartFileExclusive(fh, path)
+			if !errors.Is(err, ErrFileExists) {
+				return path, err
+			}
+		}
+
+		return "", ErrFileExists
+	default:
+		return "", fmt.Errorf("unknown collision policy %d", policy)
+	}
+}
+
+// saveMultipartFileExclusive copies fh to path, failing with ErrFileExists if path is already taken.
+func saveMultipartFileExclusive(fh *multipart.FileHeader, path string) (err error) {
+	ff, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return ErrFileExists
+		}
+
+		return err
+	}
+
+	defer func() {
+		e := ff.Close()
+		if err == nil {
+			err = e
+		}
+
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		e := f.Close()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	_, err = copyZeroAlloc(ff, f)
+
+	return err
+}
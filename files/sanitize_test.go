@@ -0,0 +1,84 @@
+package files
+
+import (
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"../../etc/passwd":      "passwd",
+		`..\..\windows\win.ini`: "win.ini",
+		"/absolute/path.txt":    "path.txt",
+		"CON.txt":               "_CON.txt",
+		"CON.tar.gz":            "_CON.tar.gz",
+		"nul.x.y":               "_nul.x.y",
+		"com1 .txt":             "_com1 .txt",
+		"CONSOLE.txt":           "CONSOLE.txt",
+		"a<b>c:d|e?f*.txt":      "a_b_c_d_e_f_.txt",
+		"evil\u202etxt.exe":     "eviltxt.exe",
+		"zero\u200bwidth.png":   "zerowidth.png",
+		"cafe\u0301.txt":        "caf\u00e9.txt",
+		"  ..hidden. . ":        "hidden",
+		"":                      "file",
+		"..":                    "file",
+		"line\nbreak\x00.txt":   "linebreak.txt",
+		"normal-name_01.tar.gz": "normal-name_01.tar.gz",
+	}
+
+	for in, want := range tests {
+		assert.Equal(t, want, SanitizeFilename(in), "SanitizeFilename(%q)", in)
+	}
+
+	long := SanitizeFilename(strings.Repeat("é", 300) + ".txt")
+	assert.LessOrEqual(t, len(long), 255)
+	assert.True(t, strings.HasSuffix(long, ".txt"))
+}
+
+func TestSafeJoin(t *testing.T) {
+	root := t.TempDir()
+
+	path, err := SafeJoin(root, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "a.txt"), path)
+
+	for _, name := range []string{"..", "../a.txt", "sub/../../a.txt", "."} {
+		_, err = SafeJoin(root, name)
+		assert.ErrorIs(t, err, ErrPathTraversal, name)
+	}
+}
+
+func newFileHeader(t *testing.T, filename, content string) *multipart.FileHeader {
+	t.Helper()
+
+	return filestest.NewMultipart(t).FileWithType("file", filename, "application/octet-stream", []byte(content)).FileHeader("file")
+}
+
+func TestSaveMultipartFileIn(t *testing.T) {
+	root := t.TempDir()
+
+	path, err := SaveMultipartFileIn(newFileHeader(t, "../../report.txt", "v1"), root, CollisionFail)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "report.txt"), path)
+
+	_, err = SaveMultipartFileIn(newFileHeader(t, "report.txt", "v2"), root, CollisionFail)
+	require.ErrorIs(t, err, ErrFileExists)
+
+	path, err = SaveMultipartFileIn(newFileHeader(t, "report.txt", "v3"), root, CollisionSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "report (1).txt"), path)
+
+	path, err = SaveMultipartFileIn(newFileHeader(t, "report.txt", "v4"), root, CollisionOverwrite)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v4", string(content))
+}
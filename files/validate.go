@@ -0,0 +1,457 @@
+package files
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// FieldViolation describes a form field that failed a validation rule.
+type FieldViolation struct {
+	// Field is the form key of the field, e.g. items[0].name.
+	Field string
+	// Rule is the failing rule as written in the tag, e.g. max=10.
+	Rule        string
+	Description string
+}
+
+// ValidationError lists every rule violated by a bound form. From ParseMultipartForm, it also holds
+// the fields that could not be bound, whose rules were not checked.
+type ValidationError struct {
+	Violations []FieldViolation
+	Binding    *BindingError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+
+	msg := "validation failed: " + strings.Join(msgs, "; ")
+
+	switch {
+	case e.Binding == nil:
+		return msg
+	case len(e.Violations) == 0:
+		return e.Binding.Error()
+	default:
+		return e.Binding.Error() + "; " + msg
+	}
+}
+
+// Unwrap exposes the binding failures, so errors.As finds the *BindingError.
+func (e *ValidationError) Unwrap() error {
+	if e.Binding == nil {
+		return nil
+	}
+
+	return e.Binding
+}
+
+// GRPCStatus converts the error to an InvalidArgument status carrying an errdetails.BadRequest,
+// so returning it from a handler is enough for clients to get the field violations.
+func (e *ValidationError) GRPCStatus() *status.Status {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if e.Binding != nil {
+		violations = e.Binding.fieldViolations()
+	}
+
+	for _, v := range e.Violations {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	return badRequestStatus(e.Error(), violations)
+}
+
+// Validate checks the `validate` tags of the struct pointed to by dst. Rules are separated by commas
+// (escape a literal comma in a regex with \,):
+//
+//	required           the field must not be empty
+//	min=n, max=n       bounds of a number, or of the length of a string, slice or map
+//	len=n              exact length of a string, slice or map
+//	oneof=a b c        allowed values
+//	regex=pattern      the string must match pattern
+//	email              the string must be an email address
+//	maxsize=10MB       maximum size of a file (B, KB, MB, GB)
+//	minsize=1KB        minimum size of a file
+//	mimetype=image/*   allowed sniffed content types of a file, space separated
+//	ext=.png .jpg      allowed filename extensions of a file
+//
+// Rules other than required are skipped for empty values. It returns a *ValidationError
+// listing every violation, or nil.
+func Validate(dst any) error {
+	v := reflect.ValueOf(dst)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var violations []FieldViolation
+
+	validateStruct(v, "", &violations)
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Violations: violations}
+}
+
+func validateStruct(v reflect.Value, prefix string, violations *[]FieldViolation) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		fieldVal := v.Field(i)
+
+		if field.Anonymous && field.Tag.Get("form") == "" {
+			if fieldVal.Kind() == reflect.Ptr {
+				if fieldVal.IsNil() {
+					continue
+				}
+
+				fieldVal = fieldVal.Elem()
+			}
+
+			if fieldVal.Kind() == reflect.Struct {
+				validateStruct(fieldVal, prefix, violations)
+			}
+
+			continue
+		}
+
+		key := formFieldName(field)
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		for _, rule := range splitRules(field.Tag.Get("validate")) {
+			if desc := checkRule(fieldVal, rule); desc != "" {
+				*violations = append(*violations, FieldViolation{Field: path, Rule: rule, Description: desc})
+			}
+		}
+
+		validateNested(fieldVal, path, violations)
+	}
+}
+
+// validateNested descends into struct fields, and into slices and maps of structs.
+func validateNested(v reflect.Value, path string, violations *[]FieldViolation) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			validateNested(v.Elem(), path, violations)
+		}
+	case reflect.Struct:
+		if isFormStruct(v.Type()) {
+			validateStruct(v, path, violations)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateNested(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), violations)
+		}
+	default:
+	}
+}
+
+// isFormStruct reports whether a struct has form or validate tags, which skips types such as time.Time
+// or multipart.FileHeader.
+func isFormStruct(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag; tag.Get("validate") != "" || tag.Get("form") != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitRules splits a validate tag on the commas that are not escaped.
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	var (
+		rules   []string
+		current strings.Builder
+	)
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			rules = append(rules, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+
+	return append(rules, current.String())
+}
+
+// checkRule returns a description of the violation, or "" when v satisfies rule.
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+	if name == "required" {
+		if isEmptyValue(v) {
+			return "is required"
+		}
+
+		return ""
+	}
+
+	if isEmptyValue(v) {
+		return ""
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch file := v.Interface().(type) {
+	case multipart.FileHeader:
+		return checkFileRule(&file, name, arg)
+	case File:
+		return checkFileRule(file.header, name, arg)
+	}
+
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && isFileRule(name) {
+		for i := 0; i < v.Len(); i++ {
+			if desc := checkRule(v.Index(i), rule); desc != "" {
+				return fmt.Sprintf("file %d %s", i, desc)
+			}
+		}
+
+		return ""
+	}
+
+	switch name {
+	case "min", "max", "len":
+		return checkBound(v, name, arg)
+	case "oneof":
+		return checkOneOf(v, arg)
+	case "regex":
+		re, err := compileRule(arg)
+		if err != nil {
+			return "has an invalid regex rule"
+		}
+
+		if v.Kind() != reflect.String || !re.MatchString(v.String()) {
+			return "must match " + arg
+		}
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if v.Kind() != reflect.String || err != nil || addr.Address != v.String() {
+			return "must be an email address"
+		}
+	default:
+		return "has an unknown rule " + name
+	}
+
+	return ""
+}
+
+func checkBound(v reflect.Value, name, arg string) string {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return "has an invalid " + name + " rule"
+	}
+
+	var (
+		actual float64
+		what   = "length"
+	)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual, what = float64(v.Int()), "value"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual, what = float64(v.Uint()), "value"
+	case reflect.Float32, reflect.Float64:
+		actual, what = v.Float(), "value"
+	case reflect.String:
+		actual = float64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual = float64(v.Len())
+	default:
+		return "does not support " + name
+	}
+
+	switch {
+	case name == "min" && actual < limit:
+		return fmt.Sprintf("%s must be at least %s", what, arg)
+	case name == "max" && actual > limit:
+		return fmt.Sprintf("%s must be at most %s", what, arg)
+	case name == "len" && actual != limit:
+		return fmt.Sprintf("%s must be exactly %s", what, arg)
+	}
+
+	return ""
+}
+
+func checkOneOf(v reflect.Value, arg string) string {
+	actual := fmt.Sprint(v.Interface())
+
+	for _, allowed := range strings.Fields(arg) {
+		if actual == allowed {
+			return ""
+		}
+	}
+
+	return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+}
+
+func isFileRule(name string) bool {
+	return name == "maxsize" || name == "minsize" || name == "mimetype" || name == "ext"
+}
+
+func checkFileRule(fh *multipart.FileHeader, name, arg string) string {
+	switch name {
+	case "maxsize", "minsize":
+		limit, err := parseByteSize(arg)
+		if err != nil {
+			return "has an invalid " + name + " rule"
+		}
+
+		if name == "maxsize" && fh.Size > limit {
+			return "must not be larger than " + arg
+		}
+
+		if name == "minsize" && fh.Size < limit {
+			return "must not be smaller than " + arg
+		}
+	case "mimetype":
+		contentType, err := sniffContentType(fh)
+		if err != nil {
+			return "could not be read"
+		}
+
+		for _, allowed := range strings.Fields(arg) {
+			if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") || contentType == allowed {
+				return ""
+			}
+		}
+
+		return fmt.Sprintf("has type %s, expected %s", contentType, strings.Join(strings.Fields(arg), ", "))
+	case "ext":
+		ext := strings.ToLower(filepath.Ext(fh.Filename))
+		for _, allowed := range strings.Fields(arg) {
+			if ext == strings.ToLower(allowed) {
+				return ""
+			}
+		}
+
+		return "must have one of the extensions " + strings.Join(strings.Fields(arg), ", ")
+	default:
+		return "does not support " + name
+	}
+
+	return ""
+}
+
+// sniffContentType detects the content type of a file from its first 512 bytes, ignoring what the client claims.
+func sniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+
+	n, err := f.Read(buf)
+	if err != nil && n == 0 && fh.Size > 0 {
+		return "", err
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(buf[:n]), ";")
+
+	return contentType, nil
+}
+
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if n, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(n), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return n * multiplier, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Invalid:
+		return true
+	default:
+		return v.IsZero()
+	}
+}
+
+var ruleRegexps sync.Map
+
+func compileRule(pattern string) (*regexp.Regexp, error) {
+	if re, ok := ruleRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleRegexps.Store(pattern, re)
+
+	return re, nil
+}
@@ -0,0 +1,156 @@
+package files
+
+import (
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type SignupItem struct {
+	Name string `form:"name" validate:"required"`
+	Qty  int    `form:"qty" validate:"min=1,max=10"`
+}
+
+type Signup struct {
+	Email   string       `form:"email" validate:"required,email"`
+	Name    string       `form:"name" validate:"min=2,max=5"`
+	Code    string       `form:"code" validate:"len=4,regex=^[A-Z]+$"`
+	Plan    string       `form:"plan" validate:"oneof=free pro"`
+	Items   []SignupItem `form:"items" validate:"max=2"`
+	Comment string       `form:"comment"`
+}
+
+func TestParseMultipartFormValidation(t *testing.T) {
+	ctx, body := newFormBody(t,
+		"email", "jane@example.com",
+		"name", "Jane",
+		"code", "ABCD",
+		"plan", "pro",
+		"items[0].name", "nut",
+		"items[0].qty", "3",
+	)
+
+	result, err := ParseMultipartForm[Signup](ctx, body)
+	require.NoError(t, err)
+	assert.Equal(t, "pro", result.Plan)
+
+	ctx, body = newFormBody(t,
+		"email", "not-an-email",
+		"name", "Jonathan",
+		"code", "ab1",
+		"plan", "gold",
+		"items[0].qty", "30",
+	)
+
+	_, err = ParseMultipartForm[Signup](ctx, body)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	fields := make([]string, 0, len(validationErr.Violations))
+	for _, v := range validationErr.Violations {
+		fields = append(fields, v.Field)
+	}
+
+	assert.Equal(t, []string{"email", "name", "code", "code", "plan", "items[0].name", "items[0].qty"}, fields)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	assert.Len(t, badRequest.GetFieldViolations(), 7)
+	assert.Equal(t, "items[0].qty", badRequest.GetFieldViolations()[6].GetField())
+}
+
+func TestParseMultipartFormBindingAndValidationErrors(t *testing.T) {
+	ctx, body := newFormBody(t,
+		"email", "not-an-email",
+		"name", "Jane",
+		"code", "ABCD",
+		"plan", "gold",
+		"items[0].name", "nut",
+		"items[0].qty", "three",
+	)
+
+	_, err := ParseMultipartForm[Signup](ctx, body)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	fields := make([]string, 0, len(validationErr.Violations))
+	for _, v := range validationErr.Violations {
+		fields = append(fields, v.Field)
+	}
+
+	// items[0].qty failed to bind, its min=1 rule isn't reported on top.
+	assert.Equal(t, []string{"email", "plan"}, fields)
+
+	var bindingErr *BindingError
+	require.ErrorAs(t, err, &bindingErr)
+	require.Len(t, bindingErr.Fields, 1)
+	assert.Equal(t, "items[0].qty", bindingErr.Fields[0].Field)
+
+	badRequest, ok := status.Convert(err).Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+
+	violations := make([]string, 0, len(badRequest.GetFieldViolations()))
+	for _, v := range badRequest.GetFieldViolations() {
+		violations = append(violations, v.GetField())
+	}
+
+	assert.Equal(t, []string{"items[0].qty", "email", "plan"}, violations)
+}
+
+func TestValidateFiles(t *testing.T) {
+	type upload struct {
+		Avatar *multipart.FileHeader   `form:"avatar" validate:"required,maxsize=1KB,mimetype=image/*,ext=.png"`
+		Docs   []*multipart.FileHeader `form:"docs" validate:"max=1,mimetype=text/plain"`
+	}
+
+	png := "\x89PNG\r\n\x1a\n" + "rest-of-image"
+
+	err := Validate(&upload{
+		Avatar: newFileHeader(t, "me.png", png),
+		Docs:   []*multipart.FileHeader{newFileHeader(t, "a.txt", "hello")},
+	})
+	require.NoError(t, err)
+
+	err = Validate(&upload{
+		Avatar: newFileHeader(t, "me.gif", "plain text, not an image"),
+		Docs:   []*multipart.FileHeader{newFileHeader(t, "a.txt", "a"), newFileHeader(t, "b.png", png)},
+	})
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	rules := make([]string, 0, len(validationErr.Violations))
+	for _, v := range validationErr.Violations {
+		rules = append(rules, v.Field+" "+v.Rule)
+	}
+
+	assert.Equal(t, []string{"avatar mimetype=image/*", "avatar ext=.png", "docs max=1", "docs mimetype=text/plain"}, rules)
+
+	err = Validate(&upload{})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "avatar", validationErr.Violations[0].Field)
+}
+
+func TestParseByteSize(t *testing.T) {
+	size, err := parseByteSize("10MB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10<<20), size)
+
+	size, err = parseByteSize("512")
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), size)
+
+	_, err = parseByteSize("ten")
+	assert.Error(t, err)
+}
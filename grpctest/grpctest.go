@@ -0,0 +1,83 @@
+// Package grpctest runs services built on server.Module and client.Gateway in memory, for tests.
+package grpctest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disco07/grpc-lib/client"
+	"github.com/disco07/grpc-lib/server"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1 << 20
+
+// Stack is a running service: its gRPC server listens on an in-memory connection and its gateway
+// on a loopback httptest server.
+type Stack struct {
+	// Conn is connected to the gRPC server.
+	Conn *grpc.ClientConn
+	// Mux is the gateway mux, as served on GatewayURL.
+	Mux *runtime.ServeMux
+	// GatewayURL is the base URL of the gateway, e.g. http://127.0.0.1:39251.
+	GatewayURL string
+	// Gateway is the HTTP server of the gateway, its Client() talks to it.
+	Gateway *httptest.Server
+}
+
+// New starts server.Module and client.Gateway with opts, typically the modules of the service under
+// test, and stops everything when the test ends. No port is bound but the gateway's loopback one,
+// so tests can run in parallel.
+//
+// The *slog.Logger, discarding everything, the context.Context and the server and client configs are
+// provided by New: change them with fx.Decorate rather than fx.Provide. Don't pass server.Module or
+// client.Module.
+func New(t testing.TB, opts ...fx.Option) *Stack {
+	t.Helper()
+
+	lis := bufconn.Listen(bufSize)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var stack Stack
+
+	app := fxtest.New(t,
+		fx.Supply(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		fx.Provide(
+			func() context.Context { return ctx },
+			// passthrough skips name resolution, the dialer connects to lis whatever the address.
+			func() server.GRPCConfigServer {
+				return server.YAMLGRPCConfigServer{ValueHost: "passthrough:///bufconn"}
+			},
+			func() client.GRPCConfigClient { return client.YAMLGRPCConfigClient{} },
+		),
+		server.WithListener(lis),
+		client.WithDialOption(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+		server.Module,
+		client.Gateway,
+		fx.Options(opts...),
+		fx.Populate(&stack.Conn, &stack.Mux),
+	)
+
+	app.RequireStart()
+
+	stack.Gateway = httptest.NewServer(client.Handler(stack.Mux))
+	stack.GatewayURL = stack.Gateway.URL
+
+	t.Cleanup(func() {
+		stack.Gateway.Close()
+		app.RequireStop()
+		cancel()
+	})
+
+	return &stack
+}
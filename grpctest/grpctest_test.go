@@ -0,0 +1,37 @@
+package grpctest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/disco07/grpc-lib/protogen/go/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for range 2 {
+		t.Run("stack", func(t *testing.T) {
+			t.Parallel()
+
+			stack := New(t)
+
+			_, err := health.NewHealthServiceClient(stack.Conn).Check(context.Background(), &emptypb.Empty{})
+			require.NoError(t, err)
+
+			resp, err := stack.Gateway.Client().Get(stack.GatewayURL + "/health")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.JSONEq(t, `{}`, string(body))
+		})
+	}
+}
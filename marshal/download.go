@@ -0,0 +1,114 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/disco07/grpc-lib/files"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// WithHTTPBodyDownloads returns a ServeMuxOption serving the downloads streamed by files.ServeContent:
+// Range and If-Range are forwarded to the gRPC handler, the status (200, 206, 304, 416) and
+// download headers it sets are written as real HTTP headers, and the chunks are written as is,
+// without the newline the gateway puts between streamed messages. It replaces the default marshaler,
+// see Marshalers.HTTPBodyDownloads to keep JSON options.
+func WithHTTPBodyDownloads() runtime.ServeMuxOption {
+	return defaultMarshalers().HTTPBodyDownloads()
+}
+
+// HTTPBodyDownloads is WithHTTPBodyDownloads with the JSON options of m. Install it after m.Default,
+// whose default marshaler would put the newline back.
+func (m Marshalers) HTTPBodyDownloads() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption(runtime.MIMEWildcard,
+			&httpBodyMarshaler{HTTPBodyMarshaler: runtime.HTTPBodyMarshaler{Marshaler: m.JSON.newJSONPb("")}})(mux)
+		runtime.WithMetadata(forwardRangeHeaders)(mux)
+		runtime.WithForwardResponseOption(applyDownloadHeaders)(mux)
+		runtime.WithMiddlewares(responseMiddleware)(mux)
+	}
+}
+
+// forwardRangeHeaders forwards the range headers that the default header matcher drops.
+func forwardRangeHeaders(_ context.Context, r *http.Request) metadata.MD {
+	md := metadata.MD{}
+
+	for _, name := range []string{"Range", "If-Range"} {
+		if v := r.Header.Get(name); v != "" {
+			md.Set(runtime.MetadataPrefix+strings.ToLower(name), v)
+		}
+	}
+
+	return md
+}
+
+func applyDownloadHeaders(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+	rw := asResponseWriter(w)
+	if rw == nil || resp != nil || rw.download {
+		return nil
+	}
+
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	values := md.HeaderMD.Get(files.DownloadStatusKey)
+	if len(values) == 0 {
+		return nil
+	}
+
+	code, err := strconv.Atoi(values[0])
+	if err != nil {
+		return nil //nolint:nilerr // not a download status we know how to apply.
+	}
+
+	rw.download = true
+	rw.status = code
+
+	header := w.Header()
+	header.Del(runtime.MetadataHeaderPrefix + files.DownloadStatusKey)
+
+	for _, key := range files.DownloadHeaders {
+		header.Del(runtime.MetadataHeaderPrefix + key)
+
+		if v := md.HeaderMD.Get(key); len(v) > 0 {
+			header.Set(key, v[0])
+		}
+	}
+
+	if code == http.StatusRequestedRangeNotSatisfiable {
+		header.Del("Transfer-Encoding")
+	}
+
+	return nil
+}
+
+// httpBodyMarshaler is the default marshaler of downloads. The gateway writes the
+// delimiter of the marshaler after every message of a stream, HttpBody chunks included, so it has
+// none and the other messages, wrapped in a result or error object, end with a newline instead.
+type httpBodyMarshaler struct {
+	runtime.HTTPBodyMarshaler
+}
+
+func (*httpBodyMarshaler) Delimiter() []byte {
+	return nil
+}
+
+func (m *httpBodyMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.HTTPBodyMarshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.(type) {
+	case map[string]interface{}, map[string]proto.Message:
+		data = append(data, '\n')
+	}
+
+	return data, nil
+}
@@ -0,0 +1,165 @@
+package marshal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/disco07/grpc-lib/files"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// downloadStream is both ends of a server stream: the gRPC handler sends to it, the gateway receives
+// what was sent, then err.
+type downloadStream struct {
+	ctx    context.Context
+	header grpcmetadata.MD
+	chunks []*httpbody.HttpBody
+	err    error
+}
+
+func (s *downloadStream) Context() context.Context { return s.ctx }
+
+func (s *downloadStream) SendHeader(md grpcmetadata.MD) error {
+	s.header = md
+	return nil
+}
+
+func (s *downloadStream) Send(body *httpbody.HttpBody) error {
+	s.chunks = append(s.chunks, proto.Clone(body).(*httpbody.HttpBody))
+	return nil
+}
+
+func (s *downloadStream) recv() (proto.Message, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+
+	return chunk, nil
+}
+
+// handleDownload serves GET path on mux like a generated server streaming handler, serve being the
+// gRPC method. It returns the number of chunks sent by the last call.
+func handleDownload(t *testing.T, mux *runtime.ServeMux, path string, serve func(files.HTTPBodySender) error) *int {
+	t.Helper()
+
+	var chunks int
+
+	require.NoError(t, mux.HandlePath(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, "/files.Service/Download", runtime.WithHTTPPathPattern(path))
+		require.NoError(t, err)
+
+		md, _ := grpcmetadata.FromOutgoingContext(ctx)
+		stream := &downloadStream{ctx: grpcmetadata.NewIncomingContext(ctx, md)}
+		stream.err = serve(stream)
+		chunks = len(stream.chunks)
+
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{HeaderMD: stream.header})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, stream.recv, mux.GetForwardResponseOptions()...)
+	}))
+
+	return &chunks
+}
+
+func TestWithHTTPBodyDownloads(t *testing.T) {
+	// Three chunks of files.ServeContent.
+	content := bytes.Repeat([]byte("0123456789"), 250_000)
+
+	mux := runtime.NewServeMux(WithHTTPBodyDownloads())
+	chunks := handleDownload(t, mux, "/report", func(stream files.HTTPBodySender) error {
+		return files.ServeContent(stream, files.Content{
+			Name:     "report.txt",
+			Size:     int64(len(content)),
+			ModTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ETag:     `"v1"`,
+			ReaderAt: bytes.NewReader(content),
+		})
+	})
+
+	get := func(headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/report", nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := get()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 3, *chunks)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=report.txt", rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Empty(t, rec.Header().Get("Grpc-Metadata-X-Download-Status"))
+	assert.True(t, bytes.Equal(content, rec.Body.Bytes()), "the body is the content, without delimiters")
+
+	rec = get("Range", "bytes=1048570-1048589")
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "bytes 1048570-1048589/2500000", rec.Header().Get("Content-Range"))
+	assert.Equal(t, string(content[1048570:1048590]), rec.Body.String())
+
+	rec = get("Range", "bytes=1000000-", "If-Range", `"v1"`)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, 2, *chunks)
+	assert.True(t, bytes.Equal(content[1000000:], rec.Body.Bytes()))
+
+	rec = get("If-None-Match", `"v1"`)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get("Transfer-Encoding"))
+	assert.Empty(t, rec.Body.String())
+
+	rec = get("Range", "bytes=3000000-")
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+	assert.Equal(t, "bytes */2500000", rec.Header().Get("Content-Range"))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestHTTPBodyMarshalerDelimitsMessageStreams(t *testing.T) {
+	mux := runtime.NewServeMux(defaultMarshalers().Default(), defaultMarshalers().HTTPBodyDownloads())
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/options", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		sent := []proto.Message{newOption(), newOption()}
+		recv := func() (proto.Message, error) {
+			if len(sent) == 0 {
+				return nil, io.EOF
+			}
+
+			msg := sent[0]
+			sent = sent[1:]
+
+			return msg, nil
+		}
+
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, recv)
+	}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/options", nil))
+
+	lines := bytes.Split(bytes.TrimSuffix(rec.Body.Bytes(), []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 2, rec.Body.String())
+	assert.Contains(t, string(lines[1]), `"result":`)
+}
@@ -0,0 +1,366 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// protobufMarshaler is the gateway's binary marshaler under the application/x-protobuf type.
+type protobufMarshaler struct {
+	runtime.ProtoMarshaller
+}
+
+func (*protobufMarshaler) ContentType(_ interface{}) string {
+	return string(FormatProtobuf)
+}
+
+// ndjsonMarshaler writes one JSON document per line. Streamed messages are written as is
+// rather than wrapped in {"result": ...}.
+type ndjsonMarshaler struct {
+	*runtime.JSONPb
+}
+
+func (*ndjsonMarshaler) ContentType(_ interface{}) string {
+	return string(FormatNDJSON)
+}
+
+func (*ndjsonMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
+
+func (m *ndjsonMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if chunk, ok := v.(map[string]interface{}); ok && len(chunk) == 1 && chunk["result"] != nil {
+		v = chunk["result"]
+	}
+
+	return m.JSONPb.Marshal(v)
+}
+
+// yamlMarshaler converts the JSON mapping of messages to and from YAML, keeping the field order.
+type yamlMarshaler struct {
+	json *runtime.JSONPb
+}
+
+func (*yamlMarshaler) ContentType(_ interface{}) string {
+	return string(FormatYAML)
+}
+
+func (m *yamlMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+
+	blockStyle(&node)
+
+	return yaml.Marshal(&node)
+}
+
+// blockStyle drops the flow and quoting styles that come from the JSON, the encoder still quotes where needed.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func (m *yamlMarshaler) Unmarshal(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return m.json.Unmarshal(jsonData, v)
+}
+
+func (m *yamlMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		data, err := io.ReadAll(io.LimitReader(r, defaultBytesSize))
+		if err != nil {
+			return err
+		}
+
+		return m.Unmarshal(data, v)
+	})
+}
+
+func (m *yamlMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		data, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+
+		return err
+	})
+}
+
+// csvMarshaler writes a response as a table: when the message has a single repeated message field,
+// such as the items of a list response, each element is a row, otherwise the message is the only row.
+// Columns are the proto names of the fields, nested messages flattened as address.city. Errors are a
+// code,message table. Every streamed message starts with the header, csvStreamMiddleware keeps the first.
+// It can't decode requests.
+type csvMarshaler struct {
+	json protojson.MarshalOptions
+}
+
+func (*csvMarshaler) ContentType(_ interface{}) string {
+	return string(FormatCSV) + "; charset=utf-8"
+}
+
+// Delimiter is empty, the rows of streamed messages end with their own newline.
+func (*csvMarshaler) Delimiter() []byte {
+	return nil
+}
+
+func (m *csvMarshaler) Marshal(v interface{}) ([]byte, error) {
+	switch chunk := v.(type) {
+	case map[string]interface{}:
+		if result, ok := chunk["result"]; ok && len(chunk) == 1 {
+			v = result
+		}
+	case map[string]proto.Message:
+		if chunkErr, ok := chunk["error"]; ok && len(chunk) == 1 {
+			v = chunkErr
+		}
+	case *status.Status:
+		v = chunk.Proto()
+	}
+
+	if st, ok := v.(*spb.Status); ok {
+		return writeCSV([][]string{{"code", "message"}, {strconv.Itoa(int(st.GetCode())), st.GetMessage()}})
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("csv: %w: %T", ErrUnsupportedFormat, v)
+	}
+
+	desc, rows := csvRows(msg.ProtoReflect())
+	columns := csvColumns(desc, "", map[protoreflect.FullName]bool{})
+	records := [][]string{columns}
+
+	for _, row := range rows {
+		record, err := m.record(row, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return writeCSV(records)
+}
+
+func writeCSV(records [][]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// csvStreamMiddleware drops the header row of the CSV written after the first, so the messages of
+// a stream make one table. An error ending the stream has other columns and keeps its header.
+func csvStreamMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		next(&csvStreamWriter{ResponseWriter: w}, r, pathParams)
+	}
+}
+
+type csvStreamWriter struct {
+	http.ResponseWriter
+
+	header []byte
+}
+
+func (w *csvStreamWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 || !strings.HasPrefix(w.Header().Get("Content-Type"), string(FormatCSV)) {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if w.header == nil {
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			w.header = bytes.Clone(b[:i+1])
+		}
+
+		return w.ResponseWriter.Write(b)
+	}
+
+	if !bytes.HasPrefix(b, w.header) {
+		return w.ResponseWriter.Write(b)
+	}
+
+	n, err := w.ResponseWriter.Write(b[len(w.header):])
+
+	return n + len(w.header), err
+}
+
+func (w *csvStreamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (m *csvMarshaler) record(row protoreflect.Message, columns []string) ([]string, error) {
+	opts := m.json
+	opts.UseProtoNames = true
+	opts.EmitUnpopulated = true
+
+	data, err := opts.Marshal(row.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		var value interface{} = fields
+
+		for _, name := range strings.Split(column, ".") {
+			if obj, ok := value.(map[string]interface{}); ok {
+				value = obj[name]
+			} else {
+				value = nil
+			}
+		}
+
+		switch value := value.(type) {
+		case nil:
+			record = append(record, "")
+		case string:
+			record = append(record, value)
+		case json.Number:
+			record = append(record, value.String())
+		default:
+			cell, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+
+			record = append(record, string(cell))
+		}
+	}
+
+	return record, nil
+}
+
+// csvRows returns the rows of m and their descriptor, known even when there is no row.
+func csvRows(m protoreflect.Message) (protoreflect.MessageDescriptor, []protoreflect.Message) {
+	var list protoreflect.FieldDescriptor
+
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !fd.IsList() || fd.Kind() != protoreflect.MessageKind {
+			continue
+		}
+
+		if list != nil {
+			return m.Descriptor(), []protoreflect.Message{m}
+		}
+
+		list = fd
+	}
+
+	if list == nil {
+		return m.Descriptor(), []protoreflect.Message{m}
+	}
+
+	elems := m.Get(list).List()
+	rows := make([]protoreflect.Message, 0, elems.Len())
+
+	for i := 0; i < elems.Len(); i++ {
+		rows = append(rows, elems.Get(i).Message())
+	}
+
+	return list.Message(), rows
+}
+
+// csvColumns lists the fields of md, flattening singular messages except well-known types. A message
+// already being flattened, on path, is a JSON column, so recursive messages end.
+func csvColumns(md protoreflect.MessageDescriptor, prefix string, path map[protoreflect.FullName]bool) []string {
+	var columns []string
+
+	path[md.FullName()] = true
+	defer delete(path, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() &&
+			!strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") && !path[fd.Message().FullName()] {
+			columns = append(columns, csvColumns(fd.Message(), name+".", path)...)
+			continue
+		}
+
+		columns = append(columns, name)
+	}
+
+	return columns
+}
+
+func (*csvMarshaler) Unmarshal(_ []byte, _ interface{}) error {
+	return fmt.Errorf("csv: %w for requests", ErrUnsupportedFormat)
+}
+
+func (*csvMarshaler) NewDecoder(_ io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(_ interface{}) error {
+		return fmt.Errorf("csv: %w for requests", ErrUnsupportedFormat)
+	})
+}
+
+func (m *csvMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		data, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+
+		return err
+	})
+}
@@ -0,0 +1,79 @@
+package marshal
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// JSONOptions is the JSON mapping shared by every marshaler of this package.
+type JSONOptions struct {
+	// UseProtoNames writes the proto field names (display_name) instead of lowerCamelCase ones.
+	UseProtoNames bool
+	// EmitUnpopulated writes fields holding their zero value.
+	EmitUnpopulated bool
+	// UseEnumNumbers writes enums as numbers instead of names.
+	UseEnumNumbers bool
+	// RejectUnknownFields fails requests carrying fields the message doesn't have.
+	RejectUnknownFields bool
+}
+
+// DefaultJSONOptions matches the grpc-gateway defaults: camelCase names, unpopulated fields written,
+// unknown fields discarded.
+func DefaultJSONOptions() JSONOptions {
+	return JSONOptions{EmitUnpopulated: true}
+}
+
+func (o JSONOptions) newJSONPb(indent string) *runtime.JSONPb {
+	return &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			Multiline:       indent != "",
+			Indent:          indent,
+			UseProtoNames:   o.UseProtoNames,
+			UseEnumNumbers:  o.UseEnumNumbers,
+			EmitUnpopulated: o.EmitUnpopulated,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: !o.RejectUnknownFields},
+	}
+}
+
+// Marshalers builds the ServeMuxOptions of this package with the same JSON options, so every
+// format a mux accepts or writes follows them.
+type Marshalers struct {
+	JSON JSONOptions
+}
+
+func defaultMarshalers() Marshalers {
+	return Marshalers{JSON: DefaultJSONOptions()}
+}
+
+// Default returns a ServeMuxOption replacing the gateway's default JSON marshaler, for every type
+// without a marshaler of its own. A pretty query parameter (?pretty) indents the response.
+func (m Marshalers) Default() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{Marshaler: m.JSON.newJSONPb("")})(mux)
+		// JSONPb answers application/json whatever type it was registered under.
+		runtime.WithMarshalerOption(formatJSONPretty, &runtime.HTTPBodyMarshaler{Marshaler: m.JSON.newJSONPb("  ")})(mux)
+		runtime.WithMiddlewares(prettyMiddleware)(mux)
+	}
+}
+
+// prettyMiddleware selects the indented marshaler when the request has ?pretty and accepts JSON.
+func prettyMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if _, ok := r.URL.Query()["pretty"]; ok && acceptsJSON(r) {
+			r = r.Clone(r.Context())
+			r.Header.Set("Accept", formatJSONPretty)
+		}
+
+		next(w, r, pathParams)
+	}
+}
+
+func acceptsJSON(r *http.Request) bool {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+
+	return accept == "" || strings.Contains(accept, "*/*") || negotiate(accept, []string{formatJSON}) != ""
+}
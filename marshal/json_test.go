@@ -0,0 +1,72 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestMarshalersJSONOptions(t *testing.T) {
+	marshalers := Marshalers{JSON: JSONOptions{UseProtoNames: true, UseEnumNumbers: true, RejectUnknownFields: true}}
+	mux := runtime.NewServeMux(marshalers.Default(), marshalers.ContentNegotiation(FormatYAML))
+
+	require.NoError(t, mux.HandlePath(http.MethodPost, "/field", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		inbound, outbound := runtime.MarshalerForRequest(mux, r)
+
+		field := &descriptorpb.FieldDescriptorProto{}
+		if err := inbound.NewDecoder(r.Body).Decode(field); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, field)
+	}))
+
+	post := func(target, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := post("/field", "", `{"json_name":"id","label":"LABEL_REPEATED"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"json_name":"id","label":3}`, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "\n")
+
+	rec = post("/field?pretty", "*/*", `{"label":3}`)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"label":3}`, rec.Body.String())
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "{\n  \"label\":"), rec.Body.String())
+
+	rec = post("/field?pretty", "application/yaml", `{"label":3}`)
+	assert.Equal(t, "label: 3\n", rec.Body.String())
+
+	rec = post("/field", "", `{"unknown":1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	lenient := DefaultJSONOptions().newJSONPb("")
+	field := &descriptorpb.FieldDescriptorProto{}
+	require.NoError(t, lenient.Unmarshal([]byte(`{"name":"id","unknown":1}`), field))
+	assert.Equal(t, "id", field.GetName())
+
+	data, err := lenient.Marshal(&descriptorpb.FieldDescriptorProto{Name: proto.String("id")})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"jsonName":`)
+}
@@ -1,27 +1,78 @@
 package marshal
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
 
+	"github.com/disco07/grpc-lib/files"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/genproto/googleapis/api/httpbody"
-	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 // 32 MB
 const defaultBytesSize = 32 << 20
 
 // WithMultipartFormMarshaler returns a ServeMuxOption which associates inbound and outbound Marshalers to a MIME type in mux.
+// HttpBody requests get the raw body, other request messages are bound from the form (see files.BindProto).
 func WithMultipartFormMarshaler() runtime.ServeMuxOption {
-	return runtime.WithMarshalerOption("multipart/form-data", &multipartFormMarshaler{
+	return defaultMarshalers().MultipartForm()
+}
+
+// WithMultipartMixedMarshaler returns a ServeMuxOption which passes multipart/mixed and multipart/related
+// bodies to HttpBody requests, to be read with files.NewParts or files.NewFormData.
+func WithMultipartMixedMarshaler() runtime.ServeMuxOption {
+	return defaultMarshalers().MultipartMixed()
+}
+
+// MultipartForm is WithMultipartFormMarshaler with the JSON options of m.
+func (m Marshalers) MultipartForm() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption("multipart/form-data", m.newMultipartFormMarshaler())(mux)
+		runtime.WithMiddlewares(formBodyMiddleware)(mux)
+	}
+}
+
+// formBody is the body of a multipart/form-data request, along with the boundary its decoder needs
+// to bind the form, as decoders only get the body.
+type formBody struct {
+	io.ReadCloser
+
+	boundary string
+}
+
+func formBodyMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if _, ok := r.Body.(*formBody); !ok {
+			mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err == nil && mediaType == "multipart/form-data" && params["boundary"] != "" {
+				r.Body = &formBody{ReadCloser: r.Body, boundary: params["boundary"]}
+			}
+		}
+
+		next(w, r, pathParams)
+	}
+}
+
+// MultipartMixed is WithMultipartMixedMarshaler with the JSON options of m.
+func (m Marshalers) MultipartMixed() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption("multipart/mixed", m.newMultipartFormMarshaler())(mux)
+		runtime.WithMarshalerOption("multipart/related", m.newMultipartFormMarshaler())(mux)
+	}
+}
+
+func (m Marshalers) newMultipartFormMarshaler() *multipartFormMarshaler {
+	return &multipartFormMarshaler{
 		HTTPBodyMarshaler: &runtime.HTTPBodyMarshaler{
-			Marshaler: &runtime.JSONPb{
-				MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
-				UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
-			},
+			Marshaler: m.JSON.newJSONPb(""),
 		},
-	})
+	}
 }
 
 type multipartFormMarshaler struct {
@@ -48,7 +99,7 @@ type multipartFormDecoder struct {
 func (d *multipartFormDecoder) Decode(v interface{}) error {
 	body, ok := v.(*httpbody.HttpBody)
 	if !ok {
-		return d.Decoder.Decode(v)
+		return d.bind(v)
 	}
 
 	if d.eof {
@@ -67,3 +118,35 @@ func (d *multipartFormDecoder) Decode(v interface{}) error {
 
 	return err
 }
+
+// bind fills a request message from a multipart/form-data body, like the urlencoded marshaler does.
+func (d *multipartFormDecoder) bind(v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot decode a form into %T", v)
+	}
+
+	body, ok := d.body.(*formBody)
+	if !ok {
+		return http.ErrNotMultipart
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, defaultBytesSize+1))
+	if err != nil {
+		return err
+	}
+
+	if len(data) > defaultBytesSize {
+		return ErrFormTooLarge
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(data), body.boundary).ReadForm(defaultBytesSize)
+	if err != nil {
+		return err
+	}
+
+	// The files are read while binding, nothing refers to them afterwards.
+	defer func() { _ = form.RemoveAll() }()
+
+	return files.BindProto(msg, form.Value, form.File)
+}
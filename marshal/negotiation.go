@@ -0,0 +1,163 @@
+package marshal
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// Format is a response format negotiated with the Accept header.
+type Format string
+
+const (
+	// FormatProtobuf is the binary protobuf encoding of the response.
+	FormatProtobuf Format = "application/x-protobuf"
+	FormatYAML     Format = "application/yaml"
+	// FormatCSV writes list responses as a table, see csvMarshaler.
+	FormatCSV Format = "text/csv"
+	// FormatNDJSON writes streamed messages one JSON document per line.
+	FormatNDJSON Format = "application/x-ndjson"
+)
+
+const (
+	formatJSON       = "application/json"
+	formatJSONPretty = "application/json+pretty"
+)
+
+// formatAliases are the other media types accepted for a format.
+var formatAliases = map[Format][]string{
+	FormatProtobuf: {"application/protobuf", "application/vnd.google.protobuf"},
+	FormatYAML:     {"application/x-yaml", "text/yaml"},
+	FormatNDJSON:   {"application/jsonl", "application/x-jsonlines"},
+}
+
+// WithContentNegotiation returns a ServeMuxOption registering the marshalers of formats and picking
+// one from the Accept header of each request, q-values and wildcards included. JSON stays the default
+// and responses get a Vary: Accept header.
+func WithContentNegotiation(formats ...Format) runtime.ServeMuxOption {
+	return defaultMarshalers().ContentNegotiation(formats...)
+}
+
+// ContentNegotiation is WithContentNegotiation with the JSON options of m.
+func (m Marshalers) ContentNegotiation(formats ...Format) runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		offers := []string{formatJSON, formatJSONPretty}
+		chosen := map[string]string{formatJSON: formatJSON, formatJSONPretty: formatJSONPretty}
+
+		for _, format := range formats {
+			fm := m.newFormatMarshaler(format)
+			if fm == nil {
+				continue
+			}
+
+			for _, mediaType := range append([]string{string(format)}, formatAliases[format]...) {
+				runtime.WithMarshalerOption(mediaType, fm)(mux)
+
+				offers = append(offers, mediaType)
+				chosen[mediaType] = string(format)
+			}
+
+			if format == FormatCSV {
+				runtime.WithMiddlewares(csvStreamMiddleware)(mux)
+			}
+		}
+
+		runtime.WithMiddlewares(negotiationMiddleware(offers, chosen))(mux)
+	}
+}
+
+func (m Marshalers) newFormatMarshaler(format Format) runtime.Marshaler {
+	switch format {
+	case FormatProtobuf:
+		return &protobufMarshaler{}
+	case FormatYAML:
+		return &yamlMarshaler{json: m.JSON.newJSONPb("")}
+	case FormatCSV:
+		return &csvMarshaler{json: m.JSON.newJSONPb("").MarshalOptions}
+	case FormatNDJSON:
+		return &ndjsonMarshaler{JSONPb: m.JSON.newJSONPb("")}
+	default:
+		return nil
+	}
+}
+
+// negotiationMiddleware rewrites Accept to the single registered media type the gateway looks up,
+// or drops it when JSON wins so the default marshaler applies.
+func negotiationMiddleware(offers []string, chosen map[string]string) runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			w.Header().Add("Vary", "Accept")
+
+			if accept := r.Header.Values("Accept"); len(accept) > 0 {
+				r = r.Clone(r.Context())
+
+				if best := negotiate(strings.Join(accept, ","), offers); best != "" && chosen[best] != formatJSON {
+					r.Header.Set("Accept", chosen[best])
+				} else {
+					r.Header.Del("Accept")
+				}
+			}
+
+			next(w, r, pathParams)
+		}
+	}
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the offer preferred by the Accept header, "" when any offer will do or none matches.
+func negotiate(accept string, offers []string) string {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	// Higher q first, then exact types before type/* before */*.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	for _, r := range ranges {
+		if r.mediaType == "*/*" {
+			return ""
+		}
+
+		for _, offer := range offers {
+			if offer == r.mediaType {
+				return offer
+			}
+
+			if prefix, ok := strings.CutSuffix(r.mediaType, "/*"); ok && strings.HasPrefix(offer, prefix+"/") {
+				return offer
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,222 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/yaml", "text/csv"}
+
+	assert.Equal(t, "application/yaml", negotiate("text/csv;q=0.5, application/yaml", offers))
+	assert.Equal(t, "text/csv", negotiate("text/*", offers))
+	assert.Equal(t, "text/csv", negotiate("*/*, text/csv", offers))
+	assert.Equal(t, "", negotiate("*/*", offers))
+	assert.Equal(t, "", negotiate("image/png, text/csv;q=0", offers))
+}
+
+func newOption() *descriptorpb.UninterpretedOption {
+	return &descriptorpb.UninterpretedOption{
+		Name: []*descriptorpb.UninterpretedOption_NamePart{
+			{NamePart: proto.String("a"), IsExtension: proto.Bool(false)},
+			{NamePart: proto.String("b,c"), IsExtension: proto.Bool(true)},
+		},
+		IdentifierValue: proto.String("id"),
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	mux := runtime.NewServeMux(WithContentNegotiation(FormatYAML, FormatCSV, FormatProtobuf, FormatNDJSON))
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/option", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, newOption())
+	}))
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/option", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := get("text/csv;q=0.5, text/yaml")
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	assert.Contains(t, rec.Body.String(), "identifierValue: id\n")
+	assert.Contains(t, rec.Body.String(), "  - namePart: a\n")
+
+	rec = get("text/csv")
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "name_part,is_extension\na,false\n\"b,c\",true\n", rec.Body.String())
+
+	rec = get("application/x-protobuf")
+	assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+
+	decoded := &descriptorpb.UninterpretedOption{}
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), decoded))
+	assert.True(t, proto.Equal(newOption(), decoded))
+
+	rec = get("")
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+}
+
+// newFolderList builds a ListFolders message of Folder rows, Folder and User referencing each other.
+func newFolderList(t *testing.T) *dynamicpb.Message {
+	t.Helper()
+
+	field := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+
+		if typeName != "" {
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			f.TypeName = proto.String(typeName)
+		}
+
+		return f
+	}
+
+	folders := field("folders", 1, ".marshal.test.Folder")
+	folders.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("marshal/csv_test.proto"),
+		Package: proto.String("marshal.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Folder"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("name", 1, ""), field("owner", 2, ".marshal.test.User")},
+			},
+			{
+				Name:  proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("id", 1, ""), field("home", 2, ".marshal.test.Folder")},
+			},
+			{
+				Name:  proto.String("ListFolders"),
+				Field: []*descriptorpb.FieldDescriptorProto{folders},
+			},
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return dynamicpb.NewMessage(fd.Messages().ByName("ListFolders"))
+}
+
+func TestCSVMarshalerRecursiveMessages(t *testing.T) {
+	m := &csvMarshaler{}
+	list := newFolderList(t)
+
+	// Without rows, only the header is written.
+	data, err := m.Marshal(list)
+	require.NoError(t, err)
+	assert.Equal(t, "name,owner.id,owner.home\n", string(data))
+
+	folders := list.Mutable(list.Descriptor().Fields().ByName("folders")).List()
+	folder := folders.NewElement().Message()
+	folder.Set(folder.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("docs"))
+
+	owner := folder.Mutable(folder.Descriptor().Fields().ByName("owner")).Message()
+	owner.Set(owner.Descriptor().Fields().ByName("id"), protoreflect.ValueOfString("u1"))
+	folders.Append(protoreflect.ValueOfMessage(folder))
+
+	data, err = m.Marshal(list)
+	require.NoError(t, err)
+	assert.Equal(t, "name,owner.id,owner.home\ndocs,u1,\n", string(data))
+}
+
+func TestCSVErrorsAndStreams(t *testing.T) {
+	mux := runtime.NewServeMux(WithContentNegotiation(FormatCSV))
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/missing", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.HTTPError(context.Background(), mux, outbound, w, r, status.Error(codes.NotFound, "no folder, here"))
+	}))
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/options", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		sent := []proto.Message{newOption(), newOption()}
+		recv := func() (proto.Message, error) {
+			if len(sent) == 0 {
+				return nil, status.Error(codes.Unavailable, "gone")
+			}
+
+			msg := sent[0]
+			sent = sent[1:]
+
+			return msg, nil
+		}
+
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, recv)
+	}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/csv")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := get("/missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "code,message\n5,\"no folder, here\"\n", rec.Body.String())
+
+	rec = get("/options")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "name_part,is_extension\na,false\n\"b,c\",true\na,false\n\"b,c\",true\n"+
+		"code,message\n14,gone\n", rec.Body.String())
+}
+
+func TestNDJSONMarshaler(t *testing.T) {
+	m := &ndjsonMarshaler{JSONPb: DefaultJSONOptions().newJSONPb("")}
+
+	data, err := m.Marshal(map[string]interface{}{"result": newOption().GetName()[0]})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"namePart":"a","isExtension":false}`, string(data))
+	assert.NotContains(t, string(data), "\n")
+	assert.Equal(t, []byte("\n"), m.Delimiter())
+}
+
+func TestYAMLMarshalerRoundTrip(t *testing.T) {
+	m := &yamlMarshaler{json: DefaultJSONOptions().newJSONPb("")}
+
+	data, err := m.Marshal(&descriptorpb.UninterpretedOption{StringValue: []byte("x"), IdentifierValue: proto.String("123")})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `identifierValue: "123"`)
+
+	decoded := &descriptorpb.UninterpretedOption{}
+	require.NoError(t, m.Unmarshal(data, decoded))
+	assert.Equal(t, "123", decoded.GetIdentifierValue())
+	assert.Equal(t, []byte("x"), decoded.GetStringValue())
+}
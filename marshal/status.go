@@ -0,0 +1,135 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/disco07/grpc-lib/metadata"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+)
+
+// WithHTTPStatus returns a ServeMuxOption answering with the status set by gRPC handlers with
+// metadata.SetHTTPStatus, metadata.Created, metadata.Redirect and the like, instead of 200.
+// The status metadata doesn't reach the client, and 204 and 304 responses have no body.
+func WithHTTPStatus() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithForwardResponseOption(applyHTTPStatus)(mux)
+		runtime.WithMiddlewares(responseMiddleware)(mux)
+	}
+}
+
+func applyHTTPStatus(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	rw := asResponseWriter(w)
+	if rw == nil || rw.wroteHeader {
+		return nil
+	}
+
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	values := md.HeaderMD.Get(metadata.HTTPStatusKey)
+	if len(values) == 0 {
+		return nil
+	}
+
+	w.Header().Del(runtime.MetadataHeaderPrefix + metadata.HTTPStatusKey)
+
+	code, err := strconv.Atoi(values[0])
+	if err != nil || code < http.StatusOK || code > 599 {
+		return nil //nolint:nilerr // not a status we know how to apply.
+	}
+
+	rw.status = code
+
+	return nil
+}
+
+// responseMiddleware wraps the writer of the gateway once, however many options need it.
+func responseMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if _, ok := w.(*responseWriter); ok {
+			next(w, r, pathParams)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w}
+
+		next(rw, r, pathParams)
+
+		// Nothing was written (204, 304, 416, empty download): the status still has to go out.
+		if rw.status != 0 && !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+// responseWriter delays the status line until the first write so the forward response options can
+// still change it. It drops the body of 204 and 304 responses.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status   int
+	download bool
+	// sent is the status written to the client, 0 until then.
+	sent        int
+	wroteHeader bool
+}
+
+func asResponseWriter(w http.ResponseWriter) *responseWriter {
+	for {
+		switch v := w.(type) {
+		case *responseWriter:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+
+	if w.status != 0 && code == http.StatusOK {
+		code = w.status
+	}
+
+	if !bodyAllowed(code) {
+		header := w.Header()
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.Del("Transfer-Encoding")
+	}
+
+	w.sent = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !bodyAllowed(w.sent) {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func bodyAllowed(code int) bool {
+	return code != http.StatusNoContent && code != http.StatusNotModified
+}
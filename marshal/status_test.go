@@ -0,0 +1,173 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files"
+	"github.com/disco07/grpc-lib/metadata"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type headerStream struct {
+	grpc.ServerTransportStream
+
+	header grpcmetadata.MD
+}
+
+func (s *headerStream) SetHeader(md grpcmetadata.MD) error {
+	s.header = grpcmetadata.Join(s.header, md)
+	return nil
+}
+
+func TestWithHTTPStatus(t *testing.T) {
+	mux := runtime.NewServeMux(WithHTTPStatus(), metadata.WithOutgoingHeaderMatcher())
+
+	handle := func(path string, handler func(ctx context.Context) error) {
+		require.NoError(t, mux.HandlePath(http.MethodPost, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			stream := &headerStream{}
+			require.NoError(t, handler(grpc.NewContextWithServerTransportStream(r.Context(), stream)))
+
+			ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{HeaderMD: stream.header})
+			_, outbound := runtime.MarshalerForRequest(mux, r)
+			runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, newOption(), mux.GetForwardResponseOptions()...)
+		}))
+	}
+
+	handle("/created", func(ctx context.Context) error { return metadata.Created(ctx, "/options/1") })
+	handle("/empty", metadata.NoContent)
+	handle("/moved", func(ctx context.Context) error { return metadata.Redirect(ctx, http.StatusSeeOther, "/elsewhere") })
+	handle("/plain", func(context.Context) error { return nil })
+
+	post := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+
+		return rec
+	}
+
+	rec := post("/created")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/options/1", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Header().Get("Grpc-Metadata-X-Http-Status"))
+	assert.Contains(t, rec.Body.String(), `"identifierValue":`)
+
+	rec = post("/empty")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Type"))
+
+	rec = post("/moved")
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/elsewhere", rec.Header().Get("Location"))
+
+	rec = post("/plain")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestWithHTTPStatusKeepsErrorBodies(t *testing.T) {
+	deny := func(context.Context, http.ResponseWriter, proto.Message) error {
+		return status.Error(codes.PermissionDenied, "denied")
+	}
+
+	mux := runtime.NewServeMux(WithHTTPStatus(), runtime.WithForwardResponseOption(deny))
+	require.NoError(t, mux.HandlePath(http.MethodDelete, "/options/1", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		stream := &headerStream{}
+		require.NoError(t, metadata.NoContent(grpc.NewContextWithServerTransportStream(r.Context(), stream)))
+
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{HeaderMD: stream.header})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, newOption(), mux.GetForwardResponseOptions()...)
+	}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/options/1", nil))
+
+	// The handler asked for 204, the error sent 403 and its body.
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "desc = denied")
+}
+
+func TestWithHTTPStatusStreams(t *testing.T) {
+	mux := runtime.NewServeMux(defaultMarshalers().Default(), WithHTTPStatus(), WithHTTPBodyDownloads())
+
+	send := func(stream files.HTTPBodySender, chunks ...string) error {
+		if err := stream.SendHeader(grpcmetadata.Pairs(files.DownloadStatusKey, "200")); err != nil {
+			return err
+		}
+
+		for _, chunk := range chunks {
+			if err := stream.Send(&httpbody.HttpBody{ContentType: "text/plain", Data: []byte(chunk)}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	handleDownload(t, mux, "/stream", func(stream files.HTTPBodySender) error {
+		return send(stream, "first,", "second,", "third")
+	})
+	handleDownload(t, mux, "/broken", func(stream files.HTTPBodySender) error {
+		if err := send(stream, "first,"); err != nil {
+			return err
+		}
+
+		return status.Error(codes.DataLoss, "disk failed")
+	})
+	handleDownload(t, mux, "/failed", func(stream files.HTTPBodySender) error {
+		if err := send(stream); err != nil {
+			return err
+		}
+
+		return status.Error(codes.NotFound, "no such file")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	rec := get("/stream")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "first,second,third", rec.Body.String())
+
+	// The status line is gone with the first chunk, the error follows it as a JSON line.
+	rec = get("/broken")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	chunk, errorLine, ok := strings.Cut(rec.Body.String(), "{")
+	require.True(t, ok, rec.Body.String())
+	assert.Equal(t, "first,", chunk)
+	assert.True(t, strings.HasSuffix(errorLine, "}\n"), errorLine)
+	assert.Contains(t, errorLine, `"error":`)
+	assert.Contains(t, errorLine, "disk failed")
+
+	// Before the first chunk, the error gets its own status.
+	rec = get("/failed")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "no such file")
+}
+
+func TestSetHTTPStatusRejectsErrors(t *testing.T) {
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &headerStream{})
+
+	require.ErrorIs(t, metadata.SetHTTPStatus(ctx, http.StatusNotFound), metadata.ErrInvalidHTTPStatus)
+	require.ErrorIs(t, metadata.Redirect(ctx, http.StatusOK, "/"), metadata.ErrInvalidHTTPStatus)
+}
@@ -0,0 +1,68 @@
+package marshal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/disco07/grpc-lib/files"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/proto"
+)
+
+const formURLEncoded = "application/x-www-form-urlencoded"
+
+var ErrFormTooLarge = errors.New("form body too large")
+
+// WithFormURLEncodedMarshaler returns a ServeMuxOption which decodes application/x-www-form-urlencoded
+// bodies into request messages with the same rules as multipart forms (see files.BindProto).
+// Responses are written as JSON.
+func WithFormURLEncodedMarshaler() runtime.ServeMuxOption {
+	return defaultMarshalers().FormURLEncoded()
+}
+
+// FormURLEncoded is WithFormURLEncodedMarshaler with the JSON options of m.
+func (m Marshalers) FormURLEncoded() runtime.ServeMuxOption {
+	return runtime.WithMarshalerOption(formURLEncoded, &formURLEncodedMarshaler{
+		Marshaler: m.JSON.newJSONPb(""),
+	})
+}
+
+type formURLEncodedMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *formURLEncodedMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		data, err := io.ReadAll(io.LimitReader(r, defaultBytesSize+1))
+		if err != nil {
+			return err
+		}
+
+		if len(data) > defaultBytesSize {
+			return ErrFormTooLarge
+		}
+
+		// Methods taking the raw body get it untouched.
+		if body, ok := v.(*httpbody.HttpBody); ok {
+			body.ContentType = formURLEncoded
+			body.Data = data
+
+			return nil
+		}
+
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("cannot decode a form into %T", v)
+		}
+
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return err
+		}
+
+		return files.BindProto(msg, values, nil)
+	})
+}
@@ -0,0 +1,148 @@
+package marshal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/disco07/grpc-lib/files/filestest"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newFormRequest builds the descriptor of a request message with the field kinds forms are bound into.
+func newFormRequest(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     kind.Enum(),
+		}
+	}
+
+	kind := field("kind", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+	kind.TypeName = proto.String(".marshal.test.Kind")
+	tags := field("tags", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	address := field("address", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	address.TypeName = proto.String(".marshal.test.FormRequest.Address")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("marshal/form_test.proto"),
+		Package: proto.String("marshal.test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_FOLDER"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("FormRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				kind,
+				tags,
+				address,
+				field("avatar", 6, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name:  proto.String("Address"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)},
+			}},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return fd.Messages().ByName("FormRequest")
+}
+
+func TestFormURLEncodedMarshaler(t *testing.T) {
+	m := &formURLEncodedMarshaler{Marshaler: &runtime.JSONPb{}}
+	desc := newFormRequest(t)
+
+	msg := dynamicpb.NewMessage(desc)
+	err := m.NewDecoder(strings.NewReader("name=docs&count=2&kind=KIND_FOLDER&tags=a&tags=b&address.city=Paris")).Decode(msg)
+	require.NoError(t, err)
+
+	want := dynamicpb.NewMessage(desc)
+	require.NoError(t, protojson.Unmarshal(
+		[]byte(`{"name":"docs","count":2,"kind":"KIND_FOLDER","tags":["a","b"],"address":{"city":"Paris"}}`), want))
+	assert.True(t, proto.Equal(want, msg), msg)
+
+	body := &httpbody.HttpBody{}
+	err = m.NewDecoder(strings.NewReader("raw=1")).Decode(body)
+	require.NoError(t, err)
+	assert.Equal(t, "raw=1", string(body.GetData()))
+
+	err = m.NewDecoder(strings.NewReader("kind=KIND_UNKNOWN")).Decode(dynamicpb.NewMessage(desc))
+	assert.Error(t, err)
+}
+
+func TestFormBodiesReachTheSameRequest(t *testing.T) {
+	var got proto.Message
+
+	desc := newFormRequest(t)
+	mux := runtime.NewServeMux(WithFormURLEncodedMarshaler(), WithMultipartFormMarshaler())
+	require.NoError(t, mux.HandlePath(http.MethodPost, "/folders", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		inbound, outbound := runtime.MarshalerForRequest(mux, r)
+
+		msg := dynamicpb.NewMessage(desc)
+		if err := inbound.NewDecoder(r.Body).Decode(msg); err != nil {
+			runtime.HTTPError(context.Background(), mux, outbound, w, r, err)
+			return
+		}
+
+		got = msg
+	}))
+
+	post := func(req *http.Request) proto.Message {
+		got = nil
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		return got
+	}
+
+	want := dynamicpb.NewMessage(desc)
+	require.NoError(t, protojson.Unmarshal(
+		[]byte(`{"name":"docs","count":2,"kind":"KIND_FOLDER","tags":["a","b"],"address":{"city":"Paris"}}`), want))
+
+	req := httptest.NewRequest(http.MethodPost, "/folders",
+		strings.NewReader(`{"name":"docs","count":2,"kind":"KIND_FOLDER","tags":["a","b"],"address":{"city":"Paris"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.True(t, proto.Equal(want, post(req)), "json")
+
+	req = httptest.NewRequest(http.MethodPost, "/folders",
+		strings.NewReader("name=docs&count=2&kind=KIND_FOLDER&tags=a&tags=b&address.city=Paris"))
+	req.Header.Set("Content-Type", formURLEncoded)
+	assert.True(t, proto.Equal(want, post(req)), "urlencoded")
+
+	form := filestest.NewMultipart(t).
+		Fields("name", "docs", "count", "2", "kind", "KIND_FOLDER", "tags", "a", "tags", "b", "address.city", "Paris").
+		File("avatar", "avatar.png", []byte("png"))
+	msg := post(form.Request(http.MethodPost, "/folders"))
+
+	want.Set(want.Descriptor().Fields().ByName("avatar"), protoreflect.ValueOfBytes([]byte("png")))
+	assert.True(t, proto.Equal(want, msg), "multipart")
+}
@@ -0,0 +1,276 @@
+package metadata
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	forwardedHeader = "forwarded"
+	xRealIPHeader   = "x-real-ip"
+)
+
+// DefaultTrustedProxies are the loopback ranges, where the gateway of this library usually runs.
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+var defaultResolver atomic.Pointer[IPResolver]
+
+func init() {
+	resolver, _ := NewIPResolver(DefaultTrustedProxies...)
+	defaultResolver.Store(resolver)
+}
+
+// SetTrustedProxies replaces the proxies trusted by ExtractMetadataFromContext, as CIDRs or single IPs.
+func SetTrustedProxies(cidrs ...string) error {
+	resolver, err := NewIPResolver(cidrs...)
+	if err != nil {
+		return err
+	}
+
+	defaultResolver.Store(resolver)
+
+	return nil
+}
+
+// ClientIP is the resolved address of a client and the proxies its request went through,
+// nearest to the client first.
+type ClientIP struct {
+	IP      string
+	Proxies []string
+}
+
+// IPResolver finds the client IP of a request, believing forwarding headers only as far as they were
+// written by trusted proxies.
+type IPResolver struct {
+	trusted []netip.Prefix
+}
+
+func NewIPResolver(cidrs ...string) (*IPResolver, error) {
+	r := &IPResolver{}
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+func (r *IPResolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve starts from the peer of the connection and walks the Forwarded, or else X-Forwarded-For,
+// chain from the right while the hops are trusted proxies. The first untrusted hop is the client.
+// When every hop is trusted, X-Real-IP, then the leftmost hop, is the client. Forwarded and X-Real-IP
+// are only read as written by WithForwardedHeaders, behind a trusted peer.
+func (r *IPResolver) Resolve(ctx context.Context) ClientIP {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var (
+		result      ClientIP
+		trustedPeer bool
+	)
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr, ok := parseHop(p.Addr.String())
+		if ok && !r.isTrusted(addr) {
+			return ClientIP{IP: addr.String()}
+		}
+
+		if ok {
+			result.IP = addr.String()
+			trustedPeer = true
+		}
+	}
+
+	var chain []string
+	if trustedPeer {
+		chain = forwardedFor(gatewayValue(md, forwardedHeader))
+	}
+
+	if len(chain) == 0 {
+		chain = forwardedForList(md.Get(xForwardedForHeader))
+	}
+
+	var proxies []string
+
+	if result.IP != "" {
+		proxies = append(proxies, result.IP)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHop(chain[i])
+		if !ok {
+			continue
+		}
+
+		result.IP = addr.String()
+
+		if !r.isTrusted(addr) {
+			result.Proxies = reverse(proxies)
+			return result
+		}
+
+		proxies = append(proxies, result.IP)
+	}
+
+	if realIP := gatewayValue(md, xRealIPHeader); trustedPeer && len(realIP) > 0 {
+		if addr, ok := parseHop(realIP[0]); ok {
+			result.IP = addr.String()
+			result.Proxies = reverse(proxies)
+
+			return result
+		}
+	}
+
+	// Every hop is a trusted proxy: the leftmost one is as close to the client as we can get.
+	if len(proxies) > 0 {
+		proxies = proxies[:len(proxies)-1]
+	}
+
+	result.Proxies = reverse(proxies)
+
+	return result
+}
+
+// incoming returns the values of a header forwarded by the gateway, or sent as metadata by a gRPC client.
+func incoming(md metadata.MD, key string) []string {
+	if values := md.Get(runtime.MetadataPrefix + key); len(values) > 0 {
+		return values
+	}
+
+	return md.Get(key)
+}
+
+// gatewayValue returns the value WithForwardedHeaders wrote for key. A client can send the same key with
+// a Grpc-Metadata- header, but the gateway adds the values of its annotators after those of the headers,
+// so only the last value is ours.
+func gatewayValue(md metadata.MD, key string) []string {
+	values := md.Get(runtime.MetadataPrefix + key)
+	if len(values) == 0 || values[len(values)-1] == "" {
+		return nil
+	}
+
+	return values[len(values)-1:]
+}
+
+// forwardedForList splits X-Forwarded-For values into hops.
+func forwardedForList(values []string) []string {
+	var hops []string
+
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	return hops
+}
+
+// forwardedFor returns the for= parameters of RFC 7239 Forwarded values.
+func forwardedFor(values []string) []string {
+	var hops []string
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hops = append(hops, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+
+	return hops
+}
+
+// parseHop parses an address as found in forwarding headers: 1.2.3.4, 1.2.3.4:80, [::1]:80 or ::1.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(hop, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func reverse(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(s))
+	for i := len(s) - 1; i >= 0; i-- {
+		out = append(out, s[i])
+	}
+
+	return out
+}
+
+// WithForwardedHeaders returns a ServeMuxOption forwarding Forwarded and X-Real-IP to the gRPC server,
+// which the default header matcher drops. Like the gateway does for X-Forwarded-For, the address of
+// the peer of the gateway is appended to Forwarded so it can't be spoofed. Both keys are always written,
+// so values a client sends with Grpc-Metadata- headers are never the last ones (see gatewayValue).
+func WithForwardedHeaders() runtime.ServeMuxOption {
+	return runtime.WithMetadata(func(_ context.Context, r *http.Request) metadata.MD {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		if strings.Contains(host, ":") {
+			host = `"[` + host + `]"`
+		}
+
+		forwarded := "for=" + host
+		if v := r.Header.Values("Forwarded"); len(v) > 0 {
+			forwarded = strings.Join(v, ", ") + ", " + forwarded
+		}
+
+		return metadata.Pairs(
+			runtime.MetadataPrefix+forwardedHeader, forwarded,
+			runtime.MetadataPrefix+xRealIPHeader, r.Header.Get("X-Real-IP"),
+		)
+	})
+}
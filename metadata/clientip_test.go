@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func newContext(peerAddr string, pairs ...string) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+	if peerAddr != "" {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerAddr), Port: 50000}})
+	}
+
+	return ctx
+}
+
+func TestIPResolver(t *testing.T) {
+	resolver, err := NewIPResolver("127.0.0.1", "10.0.0.0/8")
+	require.NoError(t, err)
+
+	// A client spoofing X-Forwarded-For behind a trusted proxy.
+	got := resolver.Resolve(newContext("127.0.0.1", "x-forwarded-for", "1.1.1.1, 203.0.113.7, 10.0.0.2"))
+	assert.Equal(t, ClientIP{IP: "203.0.113.7", Proxies: []string{"10.0.0.2", "127.0.0.1"}}, got)
+
+	// Headers sent by an untrusted peer are ignored.
+	got = resolver.Resolve(newContext("198.51.100.1", "x-forwarded-for", "1.1.1.1"))
+	assert.Equal(t, ClientIP{IP: "198.51.100.1"}, got)
+
+	got = resolver.Resolve(newContext("127.0.0.1",
+		runtime.MetadataPrefix+"forwarded", `for=192.0.2.60;proto=http, for="[2001:db8::17]:4711", for=10.1.1.1`,
+		"x-forwarded-for", "9.9.9.9"))
+	assert.Equal(t, ClientIP{IP: "2001:db8::17", Proxies: []string{"10.1.1.1", "127.0.0.1"}}, got)
+
+	got = resolver.Resolve(newContext("127.0.0.1", "x-forwarded-for", "10.0.0.3", runtime.MetadataPrefix+"x-real-ip", "192.0.2.1"))
+	assert.Equal(t, ClientIP{IP: "192.0.2.1", Proxies: []string{"10.0.0.3", "127.0.0.1"}}, got)
+
+	got = resolver.Resolve(newContext("127.0.0.1"))
+	assert.Equal(t, ClientIP{IP: "127.0.0.1"}, got)
+
+	_, err = NewIPResolver("not-a-cidr")
+	assert.Error(t, err)
+}
+
+// gatewayMetadata returns the metadata the gateway sends to the gRPC server for req.
+func gatewayMetadata(t *testing.T, req *http.Request) metadata.MD {
+	t.Helper()
+
+	var md metadata.MD
+
+	mux := runtime.NewServeMux(WithForwardedHeaders())
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/ip", func(_ http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, "/ip.Service/Get", runtime.WithHTTPPathPattern("/ip"))
+		require.NoError(t, err)
+
+		md, _ = metadata.FromOutgoingContext(ctx)
+	}))
+
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	return md
+}
+
+// resolveBehindGateway resolves md as the gRPC server does, the gateway being a trusted peer.
+func resolveBehindGateway(md metadata.MD) string {
+	ctx := peer.NewContext(metadata.NewIncomingContext(context.Background(), md),
+		&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1}})
+
+	return ExtractMetadataFromContext(ctx).IP
+}
+
+func TestWithForwardedHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	req.Header.Set("Forwarded", "for=1.1.1.1")
+	req.Header.Set("X-Real-IP", "2.2.2.2")
+	md := gatewayMetadata(t, req)
+
+	assert.Equal(t, []string{"for=1.1.1.1, for=203.0.113.9"}, md.Get(runtime.MetadataPrefix+"forwarded"))
+	assert.Equal(t, []string{"2.2.2.2"}, md.Get(runtime.MetadataPrefix+"x-real-ip"))
+	assert.Equal(t, "203.0.113.9", resolveBehindGateway(md))
+
+	// Without a Forwarded header, the peer of the gateway is still written.
+	req = httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	md = gatewayMetadata(t, req)
+
+	assert.Equal(t, []string{"for=203.0.113.9"}, md.Get(runtime.MetadataPrefix+"forwarded"))
+	assert.Equal(t, "203.0.113.9", resolveBehindGateway(md))
+}
+
+func TestWithForwardedHeadersSpoofing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "8.8.8.8:1234"
+	req.Header.Set("Grpc-Metadata-Forwarded", "for=6.6.6.6")
+	req.Header.Set("Grpc-Metadata-X-Real-IP", "6.6.6.6")
+	req.Header.Set("Grpc-Metadata-Grpcgateway-Forwarded", "for=6.6.6.6")
+	req.Header.Set("Grpc-Metadata-Grpcgateway-X-Real-IP", "6.6.6.6")
+
+	assert.Equal(t, "8.8.8.8", resolveBehindGateway(gatewayMetadata(t, req)))
+}
@@ -0,0 +1,136 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseHeaderPrefix marks the header metadata the gateway writes as plain HTTP headers.
+const responseHeaderPrefix = "http-header-"
+
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// HeaderRule selects the headers forwarded between HTTP and gRPC. Names are matched in lower case.
+type HeaderRule struct {
+	match   func(key string) bool
+	replace func(key, name string) string
+	name    string
+}
+
+// ExactHeader matches the given header names.
+func ExactHeader(names ...string) HeaderRule {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+
+	return HeaderRule{
+		match:   func(key string) bool { return set[key] },
+		replace: func(_, name string) string { return name },
+	}
+}
+
+// PrefixHeader matches the headers starting with prefix. Renamed with As, the prefix is replaced.
+func PrefixHeader(prefix string) HeaderRule {
+	prefix = strings.ToLower(prefix)
+
+	return HeaderRule{
+		match:   func(key string) bool { return strings.HasPrefix(key, prefix) },
+		replace: func(key, name string) string { return name + key[len(prefix):] },
+	}
+}
+
+// RegexHeader matches the headers matching re. Renamed with As, the matched text is replaced
+// and the name may refer to submatches, e.g. $1.
+func RegexHeader(re *regexp.Regexp) HeaderRule {
+	return HeaderRule{
+		match:   re.MatchString,
+		replace: re.ReplaceAllString,
+	}
+}
+
+// As forwards the headers matched by r under another name.
+func (r HeaderRule) As(name string) HeaderRule {
+	r.name = strings.ToLower(name)
+	return r
+}
+
+func matchHeader(rules []HeaderRule, key string) (string, bool) {
+	key = strings.ToLower(key)
+
+	for _, rule := range rules {
+		if !rule.match(key) {
+			continue
+		}
+
+		if rule.name != "" {
+			return rule.replace(key, rule.name), true
+		}
+
+		return key, true
+	}
+
+	return "", false
+}
+
+// WithIncomingHeaderMatcher returns a ServeMuxOption forwarding the HTTP headers matched by rules to
+// the gRPC server as metadata, under their own name rather than with the grpcgateway- prefix.
+// Other headers go through runtime.DefaultHeaderMatcher.
+func WithIncomingHeaderMatcher(rules ...HeaderRule) runtime.ServeMuxOption {
+	return runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+		if name, ok := matchHeader(rules, key); ok {
+			return name, true
+		}
+
+		return runtime.DefaultHeaderMatcher(key)
+	})
+}
+
+// WithOutgoingHeaderMatcher returns a ServeMuxOption writing the header metadata matched by rules as
+// HTTP headers under their own name. The headers set with SetResponseHeader and SetCookie are always
+// written, other metadata gets the Grpc-Metadata- prefix as usual.
+func WithOutgoingHeaderMatcher(rules ...HeaderRule) runtime.ServeMuxOption {
+	return runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+		if name, ok := strings.CutPrefix(key, responseHeaderPrefix); ok {
+			return name, true
+		}
+
+		if key == HTTPStatusKey {
+			return "", false
+		}
+
+		// The transport headers of the gRPC response never overwrite those of the HTTP response.
+		if key == "content-type" || strings.HasPrefix(key, "grpc-") {
+			return runtime.MetadataHeaderPrefix + key, true
+		}
+
+		if name, ok := matchHeader(rules, key); ok {
+			return name, true
+		}
+
+		return runtime.MetadataHeaderPrefix + key, true
+	})
+}
+
+// SetResponseHeader adds an HTTP header to the gateway response of the call of ctx. Like grpc.SetHeader,
+// it must be called before the handler sends its response.
+func SetResponseHeader(ctx context.Context, name, value string) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(responseHeaderPrefix+strings.ToLower(name), value))
+}
+
+// SetCookie adds a Set-Cookie header to the gateway response of the call of ctx.
+func SetCookie(ctx context.Context, cookie *http.Cookie) error {
+	if err := cookie.Valid(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCookie, err)
+	}
+
+	return SetResponseHeader(ctx, "Set-Cookie", cookie.String())
+}
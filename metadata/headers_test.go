@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestWithIncomingHeaderMatcher(t *testing.T) {
+	mux := runtime.NewServeMux(WithIncomingHeaderMatcher(
+		ExactHeader("X-Tenant-Id", "Idempotency-Key"),
+		ExactHeader("X-Api-Version").As("api-version"),
+		PrefixHeader("X-Acme-").As("acme-"),
+		RegexHeader(regexp.MustCompile(`^x-feature-(\w+)$`)).As("feature-$1"),
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("Idempotency-Key", "k1")
+	req.Header.Set("X-Api-Version", "2")
+	req.Header.Set("X-Acme-Region", "eu")
+	req.Header.Set("X-Feature-Beta", "on")
+	req.Header.Set("User-Agent", "curl/8")
+	req.Header.Set("X-Dropped", "x")
+
+	ctx, err := runtime.AnnotateContext(context.Background(), mux, req, "/svc/Method")
+	require.NoError(t, err)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Equal(t, []string{"acme"}, md.Get("x-tenant-id"))
+	assert.Equal(t, []string{"k1"}, md.Get("idempotency-key"))
+	assert.Equal(t, []string{"2"}, md.Get("api-version"))
+	assert.Equal(t, []string{"eu"}, md.Get("acme-region"))
+	assert.Equal(t, []string{"on"}, md.Get("feature-beta"))
+	assert.Equal(t, []string{"curl/8"}, md.Get("grpcgateway-user-agent"))
+	assert.Empty(t, md.Get("x-dropped"))
+}
+
+type headerStream struct {
+	grpc.ServerTransportStream
+
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestResponseHeaders(t *testing.T) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	require.NoError(t, SetResponseHeader(ctx, "Cache-Control", "no-store"))
+	require.NoError(t, SetCookie(ctx, &http.Cookie{Name: "session", Value: "s1", HttpOnly: true}))
+	require.NoError(t, SetCookie(ctx, &http.Cookie{Name: "theme", Value: "dark"}))
+	require.ErrorIs(t, SetCookie(ctx, &http.Cookie{Name: "bad name"}), ErrInvalidCookie)
+
+	stream.header.Set("x-request-id", "r1")
+	stream.header.Set("x-trace", "t1")
+	stream.header.Set("content-type", "application/grpc")
+
+	mux := runtime.NewServeMux(WithOutgoingHeaderMatcher(ExactHeader("x-request-id")))
+	ctx = runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: stream.header})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, rec, req, &emptypb.Empty{})
+
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, []string{"session=s1; HttpOnly", "theme=dark"}, rec.Header().Values("Set-Cookie"))
+	assert.Equal(t, "r1", rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, "t1", rec.Header().Get("Grpc-Metadata-X-Trace"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
@@ -1,12 +1,6 @@
 package metadata
 
-import (
-	"context"
-	"log"
-	"strings"
-
-	"google.golang.org/grpc/metadata"
-)
+import "context"
 
 const (
 	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
@@ -15,43 +9,23 @@ const (
 )
 
 type Metadata struct {
-	IP        string
+	// IP is the client address, resolved with the trusted proxies (see SetTrustedProxies).
+	IP string
+	// Proxies are the trusted proxies the request went through, nearest to the client first.
+	Proxies   []string
 	Bearer    string
 	UserAgent string
 }
 
+// ExtractMetadataFromContext returns the client IP, bearer token and user agent of a request,
+// empty when the request doesn't have them. It never returns nil.
 func ExtractMetadataFromContext(ctx context.Context) *Metadata {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		log.Println("Aucune métadonnée trouvée dans le contexte")
-		return nil
-	}
+	clientIP := Get(ctx, ClientIPKey)
 
-	m := &Metadata{}
-
-	// Extraire l'adresse IP
-	if ip := md.Get(xForwardedForHeader); len(ip) > 0 {
-		if strings.Contains(ip[0], ",") {
-			m.IP = strings.TrimSpace(strings.Split(ip[0], ",")[0])
-		} else {
-			m.IP = ip[0]
-		}
+	return &Metadata{
+		IP:        clientIP.IP,
+		Proxies:   clientIP.Proxies,
+		Bearer:    Get(ctx, BearerKey),
+		UserAgent: Get(ctx, UserAgentKey),
 	}
-
-	// Extraire le User-Agent
-	if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
-		m.UserAgent = userAgents[0]
-	}
-
-	// Extraire le Bearer token
-	if authHeaders := md.Get(authorization); len(authHeaders) > 0 {
-		for _, authHeader := range authHeaders {
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				m.Bearer = strings.TrimPrefix(authHeader, "Bearer ")
-				break
-			}
-		}
-	}
-
-	return m
 }
@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultPropagatedKeys are the request-scoped keys forwarded to downstream services by a Propagator.
+var DefaultPropagatedKeys = []string{authorization, "x-request-id", "x-tenant-id", "accept-language", "baggage"}
+
+// Propagator copies allowlisted metadata of the incoming request of a context onto the outgoing calls
+// made with it, and shortens their deadline so the caller still has time to answer when they time out.
+type Propagator struct {
+	keys   []string
+	rename map[string]string
+	margin time.Duration
+}
+
+type PropagationOption func(*Propagator)
+
+// PropagateKeys adds keys to the allowlist, which starts with DefaultPropagatedKeys.
+func PropagateKeys(keys ...string) PropagationOption {
+	return func(p *Propagator) {
+		for _, key := range keys {
+			p.keys = append(p.keys, strings.ToLower(key))
+		}
+	}
+}
+
+// OnlyKeys replaces the allowlist.
+func OnlyKeys(keys ...string) PropagationOption {
+	return func(p *Propagator) {
+		p.keys = nil
+		PropagateKeys(keys...)(p)
+	}
+}
+
+// RenameKey sends the incoming key from as to downstream, e.g. x-user-id as x-forwarded-user.
+// from is propagated even when it isn't in the allowlist.
+func RenameKey(from, to string) PropagationOption {
+	return func(p *Propagator) {
+		p.rename[strings.ToLower(from)] = strings.ToLower(to)
+	}
+}
+
+// WithDeadlineMargin makes outgoing calls time out margin before the incoming request does.
+func WithDeadlineMargin(margin time.Duration) PropagationOption {
+	return func(p *Propagator) {
+		p.margin = margin
+	}
+}
+
+func NewPropagator(opts ...PropagationOption) *Propagator {
+	p := &Propagator{
+		keys:   append([]string(nil), DefaultPropagatedKeys...),
+		rename: map[string]string{},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Outgoing returns ctx with the propagated metadata appended to its outgoing metadata. Keys the caller
+// already set on the outgoing metadata are left alone.
+func (p *Propagator) Outgoing(ctx context.Context) context.Context {
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	out, _ := metadata.FromOutgoingContext(ctx)
+
+	var kv []string
+
+	seen := map[string]bool{}
+	propagate := func(from, to string) {
+		if seen[to] || len(out.Get(to)) > 0 {
+			return
+		}
+
+		seen[to] = true
+
+		for _, v := range incoming(in, from) {
+			kv = append(kv, to, v)
+		}
+	}
+
+	for _, key := range p.keys {
+		if _, renamed := p.rename[key]; !renamed {
+			propagate(key, key)
+		}
+	}
+
+	for from, to := range p.rename {
+		propagate(from, to)
+	}
+
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// withDeadline applies the deadline margin. It fails when the incoming request has no time left
+// for the call.
+func (p *Propagator) withDeadline(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok || p.margin <= 0 {
+		return ctx, func() {}, nil
+	}
+
+	deadline = deadline.Add(-p.margin)
+	if time.Until(deadline) <= 0 {
+		return ctx, func() {}, status.Error(codes.DeadlineExceeded, "not enough time left for the outgoing call")
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+
+	return ctx, cancel, nil
+}
+
+func (p *Propagator) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		ctx, cancel, err := p.withDeadline(ctx)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		return invoker(p.Outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func (p *Propagator) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, cancel, err := p.withDeadline(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := streamer(p.Outgoing(ctx), desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		return &clientStream{ClientStream: stream, cancel: cancel, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// DialOptions installs the interceptors of p on a client connection.
+func (p *Propagator) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(p.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(p.StreamClientInterceptor()),
+	}
+}
+
+// clientStream releases the deadline of the stream once it is over: after an error, or after the
+// response of a method that doesn't stream responses.
+type clientStream struct {
+	grpc.ClientStream
+
+	cancel        context.CancelFunc
+	serverStreams bool
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.cancel()
+	}
+
+	return err
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestPropagatorUnaryClientInterceptor(t *testing.T) {
+	p := NewPropagator(PropagateKeys("X-Session"), RenameKey("x-user-id", "x-forwarded-user"), WithDeadlineMargin(time.Second))
+	interceptor := p.UnaryClientInterceptor()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"authorization", "Bearer abc",
+		"grpcgateway-accept-language", "fr",
+		"x-session", "s1",
+		"x-user-id", "42",
+		"cookie", "secret",
+	))
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "set-by-caller")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	incomingDeadline, _ := ctx.Deadline()
+
+	var out metadata.MD
+
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		out, _ = metadata.FromOutgoingContext(ctx)
+
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		assert.Equal(t, incomingDeadline.Add(-time.Second), deadline)
+
+		return nil
+	}
+	require.NoError(t, interceptor(ctx, "/svc/Method", nil, nil, nil, invoker))
+
+	assert.Equal(t, []string{"Bearer abc"}, out.Get("authorization"))
+	assert.Equal(t, []string{"fr"}, out.Get("accept-language"))
+	assert.Equal(t, []string{"s1"}, out.Get("x-session"))
+	assert.Equal(t, []string{"42"}, out.Get("x-forwarded-user"))
+	assert.Equal(t, []string{"set-by-caller"}, out.Get("x-request-id"))
+	assert.Empty(t, out.Get("x-user-id"))
+	assert.Empty(t, out.Get("cookie"))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	err := interceptor(ctx, "/svc/Method", nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		t.Fatal("call made without time left")
+		return nil
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestPropagatorOnlyKeys(t *testing.T) {
+	p := NewPropagator(OnlyKeys("x-tenant-id"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer abc", "x-tenant-id", "acme"))
+
+	out, _ := metadata.FromOutgoingContext(p.Outgoing(ctx))
+	assert.Equal(t, metadata.Pairs("x-tenant-id", "acme"), out)
+}
+
+type recvStream struct {
+	grpc.ClientStream
+
+	ctx  context.Context
+	errs []error
+}
+
+func (s *recvStream) RecvMsg(any) error {
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+
+	return err
+}
+
+func TestPropagatorStreamClientInterceptorReleasesDeadline(t *testing.T) {
+	interceptor := NewPropagator(WithDeadlineMargin(time.Second)).StreamClientInterceptor()
+
+	open := func(desc *grpc.StreamDesc, errs ...error) (grpc.ClientStream, *recvStream) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		t.Cleanup(cancel)
+
+		var inner *recvStream
+
+		streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			inner = &recvStream{ctx: ctx, errs: errs}
+			return inner, nil
+		}
+
+		stream, err := interceptor(ctx, desc, nil, "/svc/Method", streamer)
+		require.NoError(t, err)
+
+		return stream, inner
+	}
+
+	// Client streaming: the single response ends the call.
+	stream, inner := open(&grpc.StreamDesc{ClientStreams: true}, nil)
+	require.NoError(t, stream.RecvMsg(nil))
+	assert.Error(t, inner.ctx.Err())
+
+	// Server streaming: the call goes on until RecvMsg fails, io.EOF included.
+	stream, inner = open(&grpc.StreamDesc{ServerStreams: true}, nil, io.EOF)
+	require.NoError(t, stream.RecvMsg(nil))
+	assert.NoError(t, inner.ctx.Err())
+	require.ErrorIs(t, stream.RecvMsg(nil), io.EOF)
+	assert.Error(t, inner.ctx.Err())
+}
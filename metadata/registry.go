@@ -0,0 +1,210 @@
+package metadata
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Extractor reads one value from the metadata of a request. *Key[T] is the implementation.
+type Extractor interface {
+	Name() string
+	extractAny(ctx context.Context, md metadata.MD) (any, bool, error)
+}
+
+// Key is a typed metadata value, read once per request by the interceptors of a Registry and
+// retrieved with Get.
+type Key[T any] struct {
+	name    string
+	def     T
+	extract func(ctx context.Context, md metadata.MD) (T, bool, error)
+}
+
+// NewKey declares a value read from a header, or a metadata key for gRPC clients, and parsed with parse.
+// Requests without it get def.
+func NewKey[T any](name string, parse func(string) (T, error), def T) *Key[T] {
+	name = strings.ToLower(name)
+
+	return NewKeyFunc(name, func(_ context.Context, md metadata.MD) (T, bool, error) {
+		values := incoming(md, name)
+		if len(values) == 0 || values[0] == "" {
+			var zero T
+			return zero, false, nil
+		}
+
+		v, err := parse(values[0])
+
+		return v, err == nil, err
+	}, def)
+}
+
+// NewKeyFunc declares a value computed from the whole request, e.g. from several headers or the peer.
+// extract reports whether the value was found.
+func NewKeyFunc[T any](name string, extract func(ctx context.Context, md metadata.MD) (T, bool, error), def T) *Key[T] {
+	return &Key[T]{name: name, def: def, extract: extract}
+}
+
+// StringKey declares a value read as is from a header.
+func StringKey(name, def string) *Key[string] {
+	return NewKey(name, func(s string) (string, error) { return s, nil }, def)
+}
+
+func (k *Key[T]) Name() string {
+	return k.name
+}
+
+func (k *Key[T]) Default() T {
+	return k.def
+}
+
+func (k *Key[T]) extractAny(ctx context.Context, md metadata.MD) (any, bool, error) {
+	return k.extract(ctx, md)
+}
+
+var (
+	// ClientIPKey is the client address, see IPResolver and SetTrustedProxies.
+	ClientIPKey = NewKeyFunc("client-ip", func(ctx context.Context, _ metadata.MD) (ClientIP, bool, error) {
+		clientIP := defaultResolver.Load().Resolve(ctx)
+		return clientIP, clientIP.IP != "", nil
+	}, ClientIP{})
+	// BearerKey is the token of an Authorization: Bearer header.
+	BearerKey = NewKeyFunc(authorization, func(_ context.Context, md metadata.MD) (string, bool, error) {
+		for _, authHeader := range md.Get(authorization) {
+			if token, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
+				return token, true, nil
+			}
+		}
+
+		return "", false, nil
+	}, "")
+	// UserAgentKey is the User-Agent of the HTTP client of the gateway, or of the gRPC client.
+	UserAgentKey = NewKeyFunc("user-agent", func(_ context.Context, md metadata.MD) (string, bool, error) {
+		for _, key := range []string{grpcGatewayUserAgentHeader, "user-agent"} {
+			if userAgents := md.Get(key); len(userAgents) > 0 {
+				return userAgents[0], true, nil
+			}
+		}
+
+		return "", false, nil
+	}, "")
+)
+
+// DefaultRegistry holds the keys of Metadata. Applications add theirs with Register.
+var DefaultRegistry = NewRegistry(ClientIPKey, BearerKey, UserAgentKey)
+
+// Registry is a set of keys extracted together by its interceptors.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+}
+
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{extractors: extractors}
+}
+
+// Register adds keys to the registry. It is meant to be called at startup.
+func (r *Registry) Register(extractors ...Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.extractors = append(r.extractors, extractors...)
+}
+
+type contextKey struct{}
+
+type entry struct {
+	value any
+	ok    bool
+}
+
+// Extract reads every key of the registry and stores the values in the returned context.
+// A value that fails to parse is an InvalidArgument error.
+func (r *Registry) Extract(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	values := make(map[Extractor]entry, len(r.extractors))
+
+	for _, e := range r.extractors {
+		v, ok, err := e.extractAny(ctx, md)
+		if err != nil {
+			return ctx, status.Errorf(codes.InvalidArgument, "invalid %s metadata: %v", e.Name(), err)
+		}
+
+		values[e] = entry{value: v, ok: ok}
+	}
+
+	return context.WithValue(ctx, contextKey{}, values), nil
+}
+
+func (r *Registry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := r.Extract(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (r *Registry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := r.Extract(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Get returns the value of key for the request of ctx, or its default.
+func Get[T any](ctx context.Context, key *Key[T]) T {
+	if v, ok := Lookup(ctx, key); ok {
+		return v
+	}
+
+	return key.def
+}
+
+// Lookup returns the value of key and whether the request had it. Keys the interceptor didn't extract,
+// because they aren't registered or the interceptor isn't installed, are read from ctx on the spot.
+func Lookup[T any](ctx context.Context, key *Key[T]) (T, bool) {
+	values, _ := ctx.Value(contextKey{}).(map[Extractor]entry)
+
+	if e, ok := values[key]; ok {
+		if !e.ok {
+			var zero T
+			return zero, false
+		}
+
+		return e.value.(T), true //nolint:forcetypeassert // only a Key[T] stores values under itself.
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	v, ok, err := key.extract(ctx, md)
+	if err != nil {
+		return v, false
+	}
+
+	return v, ok
+}
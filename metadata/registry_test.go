@@ -0,0 +1,66 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	tenantKey        = StringKey("X-Tenant-ID", "")
+	localeKey        = StringKey("accept-language", "en")
+	clientVersionKey = NewKey("x-client-version", strconv.Atoi, 1)
+)
+
+func TestRegistryInterceptor(t *testing.T) {
+	registry := NewRegistry(tenantKey, localeKey, clientVersionKey, UserAgentKey)
+	interceptor := registry.UnaryServerInterceptor()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant-id", "acme",
+		"x-client-version", "3",
+		"grpcgateway-user-agent", "curl/8",
+	))
+
+	var handlerCtx context.Context
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		handlerCtx = ctx
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "acme", Get(handlerCtx, tenantKey))
+	assert.Equal(t, "en", Get(handlerCtx, localeKey))
+	assert.Equal(t, 3, Get(handlerCtx, clientVersionKey))
+	assert.Equal(t, "curl/8", Get(handlerCtx, UserAgentKey))
+
+	_, ok := Lookup(handlerCtx, localeKey)
+	assert.False(t, ok)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-client-version", "v3"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+		t.Fatal("handler called with invalid metadata")
+		return nil, nil
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetWithoutInterceptor(t *testing.T) {
+	assert.Equal(t, 1, Get(context.Background(), clientVersionKey))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-client-version", "7", "authorization", "Bearer abc"))
+	assert.Equal(t, 7, Get(ctx, clientVersionKey))
+
+	m := ExtractMetadataFromContext(context.Background())
+	require.NotNil(t, m)
+	assert.Equal(t, "", m.Bearer)
+	assert.Equal(t, "abc", ExtractMetadataFromContext(ctx).Bearer)
+}
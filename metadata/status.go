@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// HTTPStatusKey is the header metadata key carrying the HTTP status of a gateway response.
+// The gateway side (marshal.WithHTTPStatus) applies and strips it.
+const HTTPStatusKey = "x-http-status"
+
+var ErrInvalidHTTPStatus = errors.New("invalid HTTP status")
+
+// SetHTTPStatus sets the status of the gateway response of the call of ctx, in place of 200.
+// Only success and redirect statuses are accepted: errors are returned as gRPC status errors.
+// gRPC clients still get OK.
+func SetHTTPStatus(ctx context.Context, code int) error {
+	if code < http.StatusOK || code >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %d", ErrInvalidHTTPStatus, code)
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(HTTPStatusKey, strconv.Itoa(code)))
+}
+
+// Created answers 201 Created with the location of the new resource.
+func Created(ctx context.Context, location string) error {
+	if err := SetResponseHeader(ctx, "Location", location); err != nil {
+		return err
+	}
+
+	return SetHTTPStatus(ctx, http.StatusCreated)
+}
+
+// Accepted answers 202 Accepted, with the location of a status monitor when not empty.
+func Accepted(ctx context.Context, location string) error {
+	if location != "" {
+		if err := SetResponseHeader(ctx, "Location", location); err != nil {
+			return err
+		}
+	}
+
+	return SetHTTPStatus(ctx, http.StatusAccepted)
+}
+
+// NoContent answers 204 No Content, the response message is dropped.
+func NoContent(ctx context.Context) error {
+	return SetHTTPStatus(ctx, http.StatusNoContent)
+}
+
+// Redirect answers with a 3xx status and the location to go to.
+func Redirect(ctx context.Context, code int, location string) error {
+	if code < http.StatusMultipleChoices || code >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %d is not a redirect", ErrInvalidHTTPStatus, code)
+	}
+
+	if err := SetResponseHeader(ctx, "Location", location); err != nil {
+		return err
+	}
+
+	return SetHTTPStatus(ctx, code)
+}
@@ -1,5 +1,15 @@
 package server
 
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidPort  = errors.New("must be between 1 and 65535")
+	ErrHostRequired = errors.New("is required")
+)
+
 type GRPCConfigServer interface {
 	Host() string
 	Port() int
@@ -17,3 +27,27 @@ func (c YAMLGRPCConfigServer) Port() int {
 func (c YAMLGRPCConfigServer) Host() string {
 	return c.ValueHost
 }
+
+// Validate reports every invalid setting, named after its yaml key.
+func (c YAMLGRPCConfigServer) Validate() error {
+	var errs []error
+
+	if err := ValidatePort(c.ValuePort); err != nil {
+		errs = append(errs, fmt.Errorf("port: %w", err))
+	}
+
+	if c.ValueHost == "" {
+		errs = append(errs, fmt.Errorf("host: %w", ErrHostRequired))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidatePort checks that port can be listened on.
+func ValidatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%w, got %d", ErrInvalidPort, port)
+	}
+
+	return nil
+}
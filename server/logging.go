@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -19,6 +20,25 @@ const (
 	FgWhite  = "\033[97m"
 )
 
+// DefaultLoggingSkip are the methods LoggingInterceptor doesn't log, until SetLoggingSkip is called.
+var DefaultLoggingSkip = []string{"/health.HealthService/Check"}
+
+var loggingSkip atomic.Pointer[map[string]bool]
+
+func init() {
+	SetLoggingSkip(DefaultLoggingSkip...)
+}
+
+// SetLoggingSkip replaces the methods LoggingInterceptor doesn't log. It can be called while serving.
+func SetLoggingSkip(methods ...string) {
+	skip := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		skip[method] = true
+	}
+
+	loggingSkip.Store(&skip)
+}
+
 // getStatusColor returns the background color for a given status code
 func getStatusColor(code codes.Code) string {
 	switch {
@@ -47,7 +67,7 @@ func LoggingInterceptor() grpc.UnaryServerInterceptor {
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
 		// Skip logging for specific methods
-		if info.FullMethod == "/health.HealthService/Check" {
+		if (*loggingSkip.Load())[info.FullMethod] {
 			return handler(ctx, req)
 		}
 
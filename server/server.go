@@ -7,6 +7,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/disco07/grpc-lib/metadata"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/fx"
 	"google.golang.org/grpc"
@@ -14,10 +15,43 @@ import (
 	"google.golang.org/grpc/reflection"
 )
 
-func newGPRCServer(lifecycle fx.Lifecycle, logger *slog.Logger, config GRPCConfigServer) grpc.ServiceRegistrar {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port()))
-	if err != nil {
-		logger.Warn(err.Error())
+type serverParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *slog.Logger
+	Config    GRPCConfigServer
+	// Registry lists the metadata extracted into the context of every call, metadata.DefaultRegistry when not provided.
+	Registry *metadata.Registry `optional:"true"`
+	// Listener replaces the TCP listener on the configured port, see WithListener.
+	Listener net.Listener `name:"grpc_listener" optional:"true"`
+}
+
+// WithListener makes the server of Module serve on lis, e.g. a bufconn listener in tests, rather than
+// listen on the configured port.
+func WithListener(lis net.Listener) fx.Option {
+	return fx.Provide(fx.Annotate(
+		func() net.Listener { return lis },
+		fx.ResultTags(`name:"grpc_listener"`),
+	))
+}
+
+func newGPRCServer(params serverParams) grpc.ServiceRegistrar {
+	lifecycle, logger, config := params.Lifecycle, params.Logger, params.Config
+
+	registry := params.Registry
+	if registry == nil {
+		registry = metadata.DefaultRegistry
+	}
+
+	listener := params.Listener
+	if listener == nil {
+		var err error
+
+		listener, err = net.Listen("tcp", fmt.Sprintf(":%d", config.Port()))
+		if err != nil {
+			logger.Warn(err.Error())
+		}
 	}
 
 	keepaliveOptions := grpc.KeepaliveParams(keepalive.ServerParameters{
@@ -34,7 +68,8 @@ func newGPRCServer(lifecycle fx.Lifecycle, logger *slog.Logger, config GRPCConfi
 		keepaliveOptions,
 		keepaliveEnforcementOptions,
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-		grpc.UnaryInterceptor(LoggingInterceptor()),
+		grpc.ChainUnaryInterceptor(registry.UnaryServerInterceptor(), LoggingInterceptor()),
+		grpc.ChainStreamInterceptor(registry.StreamServerInterceptor()),
 	)
 
 	lifecycle.Append(fx.Hook{
@@ -42,7 +77,7 @@ func newGPRCServer(lifecycle fx.Lifecycle, logger *slog.Logger, config GRPCConfi
 			go func() {
 				reflection.Register(server)
 
-				if err = server.Serve(listener); err != nil {
+				if err := server.Serve(listener); err != nil {
 					logger.Warn(err.Error())
 				}
 			}()
@@ -0,0 +1,278 @@
+package tus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound       = errors.New("upload not found")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+)
+
+// Upload holds the state of a resumable upload.
+type Upload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (u *Upload) Complete() bool {
+	return u.Offset >= u.Size
+}
+
+// Expired reports whether the upload is unfinished and expired before now. As in the tus expiration
+// extension, completed uploads never expire.
+func (u *Upload) Expired(now time.Time) bool {
+	return !u.Complete() && !u.ExpiresAt.IsZero() && now.After(u.ExpiresAt)
+}
+
+// Store persists the state and content of partial uploads.
+type Store interface {
+	// Create registers a new, empty upload.
+	Create(ctx context.Context, upload Upload) error
+	// Get returns the upload with the given id or ErrNotFound.
+	Get(ctx context.Context, id string) (*Upload, error)
+	// WriteChunk appends r to the upload at offset and returns the number of bytes written.
+	// It returns ErrOffsetMismatch if offset is not the current upload offset.
+	WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Open returns a reader over the bytes received so far.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes the upload and its content.
+	Delete(ctx context.Context, id string) error
+	// DeleteExpired removes every upload that expired before now (see Upload.Expired) and returns how many were removed.
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// FileStore is a Store keeping each upload in a directory as <id>.bin (content) and <id>.info (JSON state).
+type FileStore struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewFileStore returns a FileStore writing into dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+func (s *FileStore) Create(_ context.Context, upload Upload) error {
+	if !validID(upload.ID) {
+		return fmt.Errorf("invalid upload id %q", upload.ID)
+	}
+
+	unlock := s.lock(upload.ID)
+	defer unlock()
+
+	f, err := os.OpenFile(s.binPath(upload.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return s.writeInfo(&upload)
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (*Upload, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	return s.readInfo(id)
+}
+
+func (s *FileStore) WriteChunk(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	upload, err := s.readInfo(id)
+	if err != nil {
+		return 0, err
+	}
+
+	if upload.Offset != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.binPath(id), os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// A broken connection still leaves the bytes already received usable, so the offset is
+	// saved even when the copy fails.
+	n, copyErr := io.Copy(f, io.LimitReader(r, upload.Size-offset))
+
+	upload.Offset += n
+	if err = s.writeInfo(upload); err != nil {
+		return n, err
+	}
+
+	return n, copyErr
+}
+
+func (s *FileStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	f, err := os.Open(s.binPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	err := errors.Join(os.Remove(s.binPath(id)), os.Remove(s.infoPath(id)))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *FileStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		count int
+		errs  []error
+	)
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok {
+			continue
+		}
+
+		upload, err := s.Get(ctx, id)
+		if err != nil || !upload.Expired(now) {
+			continue
+		}
+
+		if err = s.Delete(ctx, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		count++
+	}
+
+	return count, errors.Join(errs...)
+}
+
+func (s *FileStore) lock(id string) func() {
+	s.mu.Lock()
+
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+
+	s.mu.Unlock()
+
+	l.Lock()
+
+	return l.Unlock
+}
+
+func (s *FileStore) readInfo(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &Upload{}
+	if err = json.Unmarshal(data, upload); err != nil {
+		return nil, fmt.Errorf("corrupted upload info %s: %w", id, err)
+	}
+
+	return upload, nil
+}
+
+// writeInfo writes the upload state through a temporary file so readers never see a partial file.
+func (s *FileStore) writeInfo(upload *Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.infoPath(upload.ID))
+}
+
+func (s *FileStore) binPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *FileStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+// validID makes sure an id coming from the URL can't be used to leave the store directory.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
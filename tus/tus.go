@@ -0,0 +1,434 @@
+// Package tus implements the core of the tus 1.0 resumable upload protocol (https://tus.io/protocols/resumable-upload)
+// with the creation, expiration and termination extensions, so it can be mounted next to the gRPC gateway routes.
+package tus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	Version    = "1.0.0"
+	Extensions = "creation,expiration,termination"
+
+	offsetOctetStream = "application/offset+octet-stream"
+	defaultExpiration = 24 * time.Hour
+)
+
+const (
+	headerTusResumable   = "Tus-Resumable"
+	headerTusVersion     = "Tus-Version"
+	headerTusExtension   = "Tus-Extension"
+	headerTusMaxSize     = "Tus-Max-Size"
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadLength   = "Upload-Length"
+	headerUploadMetadata = "Upload-Metadata"
+	headerUploadExpires  = "Upload-Expires"
+	headerMethodOverride = "X-HTTP-Method-Override"
+)
+
+// Headers lists the request and response headers used by the protocol, e.g. to configure CORS.
+var Headers = []string{
+	headerTusResumable, headerTusVersion, headerTusExtension, headerTusMaxSize,
+	headerUploadOffset, headerUploadLength, headerUploadMetadata, headerUploadExpires,
+	headerMethodOverride, "Location",
+}
+
+// CompleteFunc is called once all the bytes of an upload have been received.
+// content reads the assembled file. A gRPC status error is translated to the matching HTTP status.
+type CompleteFunc func(ctx context.Context, upload *Upload, content io.Reader) error
+
+// Config configures a Handler.
+type Config struct {
+	// BasePath is the URL path uploads are created on, e.g. "/uploads".
+	BasePath string
+	// Store persists partial uploads.
+	Store Store
+	// MaxSize is the largest accepted upload in bytes. Zero means no limit.
+	MaxSize int64
+	// Expiration is how long an unfinished upload is kept after its creation. Defaults to 24h.
+	Expiration time.Duration
+	// OnComplete processes finished uploads.
+	OnComplete CompleteFunc
+	// DeleteOnComplete removes the upload from the store once OnComplete succeeded.
+	DeleteOnComplete bool
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Handler serves the tus protocol.
+type Handler struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewHandler validates cfg and returns a Handler.
+func NewHandler(cfg Config) (*Handler, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("tus: store is required")
+	}
+
+	cfg.BasePath = "/" + strings.Trim(cfg.BasePath, "/")
+	if cfg.BasePath == "/" {
+		return nil, errors.New("tus: base path is required")
+	}
+
+	if cfg.Expiration <= 0 {
+		cfg.Expiration = defaultExpiration
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &Handler{cfg: cfg, now: time.Now}, nil
+}
+
+// Register mounts the handler on the gateway mux under the configured base path.
+func (h *Handler) Register(mux *runtime.ServeMux) error {
+	collection := h.cfg.BasePath
+	resource := h.cfg.BasePath + "/{id}"
+
+	routes := []struct {
+		method  string
+		pattern string
+	}{
+		{http.MethodOptions, collection},
+		{http.MethodPost, collection},
+		{http.MethodOptions, resource},
+		{http.MethodHead, resource},
+		{http.MethodPatch, resource},
+		{http.MethodDelete, resource},
+		// Clients that can't send PATCH or DELETE use POST with X-HTTP-Method-Override.
+		{http.MethodPost, resource},
+	}
+
+	for _, route := range routes {
+		err := mux.HandlePath(route.method, route.pattern, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			h.serve(w, r, pathParams["id"])
+		})
+		if err != nil {
+			return fmt.Errorf("tus: register %s %s: %w", route.method, route.pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// ServeHTTP serves the protocol on a plain net/http mux, e.g. mux.Handle("/uploads/", handler).
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, h.cfg.BasePath)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.serve(w, r, strings.Trim(rest, "/"))
+}
+
+// PurgeExpired removes the uploads that expired. It is meant to be called periodically.
+func (h *Handler) PurgeExpired(ctx context.Context) (int, error) {
+	return h.cfg.Store.DeleteExpired(ctx, h.now())
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, id string) {
+	method := r.Method
+	if override := r.Header.Get(headerMethodOverride); override != "" && method == http.MethodPost {
+		method = strings.ToUpper(override)
+	}
+
+	w.Header().Set(headerTusResumable, Version)
+
+	if method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+
+	if r.Header.Get(headerTusResumable) != Version {
+		w.Header().Set(headerTusVersion, Version)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+
+		return
+	}
+
+	switch {
+	case id == "" && method == http.MethodPost:
+		h.create(w, r)
+	case id != "" && method == http.MethodHead:
+		h.head(w, r, id)
+	case id != "" && method == http.MethodPatch:
+		h.patch(w, r, id)
+	case id != "" && method == http.MethodDelete:
+		h.terminate(w, r, id)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) options(w http.ResponseWriter) {
+	w.Header().Set(headerTusVersion, Version)
+	w.Header().Set(headerTusExtension, Extensions)
+
+	if h.cfg.MaxSize > 0 {
+		w.Header().Set(headerTusMaxSize, strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get(headerUploadLength), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+
+	if h.cfg.MaxSize > 0 && size > h.cfg.MaxSize {
+		http.Error(w, "upload exceeds Tus-Max-Size", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	meta, err := parseMetadata(r.Header.Get(headerUploadMetadata))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		h.internalError(w, "generate upload id", err)
+		return
+	}
+
+	now := h.now()
+	upload := Upload{
+		ID:        id,
+		Size:      size,
+		Metadata:  meta,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.cfg.Expiration),
+	}
+
+	if err = h.cfg.Store.Create(r.Context(), upload); err != nil {
+		h.internalError(w, "create upload", err)
+		return
+	}
+
+	w.Header().Set("Location", h.cfg.BasePath+"/"+id)
+	w.Header().Set(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	// An empty upload is complete as soon as it exists.
+	if upload.Complete() && !h.complete(w, r, &upload) {
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request, id string) {
+	upload, ok := h.lookup(w, r, id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set(headerUploadLength, strconv.FormatInt(upload.Size, 10))
+
+	if len(upload.Metadata) > 0 {
+		w.Header().Set(headerUploadMetadata, formatMetadata(upload.Metadata))
+	}
+
+	if !upload.ExpiresAt.IsZero() && !upload.Complete() {
+		w.Header().Set(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != offsetOctetStream {
+		http.Error(w, "Content-Type must be "+offsetOctetStream, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	upload, ok := h.lookup(w, r, id)
+	if !ok {
+		return
+	}
+
+	if offset != upload.Offset {
+		http.Error(w, ErrOffsetMismatch.Error(), http.StatusConflict)
+		return
+	}
+
+	if r.ContentLength > upload.Size-upload.Offset {
+		http.Error(w, "chunk exceeds Upload-Length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	n, err := h.cfg.Store.WriteChunk(r.Context(), id, offset, r.Body)
+
+	switch {
+	case errors.Is(err, ErrOffsetMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil && n == 0:
+		h.internalError(w, "write chunk", err)
+		return
+	case err != nil:
+		// The client went away mid-chunk; what was received is kept and HEAD reports it.
+		h.cfg.Logger.Warn("tus: partial chunk", slog.String("id", id), slog.Int64("written", n), slog.Any("error", err))
+	}
+
+	upload.Offset += n
+
+	w.Header().Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	if !upload.ExpiresAt.IsZero() && !upload.Complete() {
+		w.Header().Set(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	if upload.Complete() && !h.complete(w, r, upload) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request, id string) {
+	err := h.cfg.Store.Delete(r.Context(), id)
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.NotFound(w, r)
+	case err != nil:
+		h.internalError(w, "delete upload", err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// lookup loads an upload and writes the error response when it is missing or expired.
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request, id string) (*Upload, bool) {
+	upload, err := h.cfg.Store.Get(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	if err != nil {
+		h.internalError(w, "get upload", err)
+		return nil, false
+	}
+
+	if upload.Expired(h.now()) {
+		if err = h.cfg.Store.Delete(r.Context(), id); err != nil && !errors.Is(err, ErrNotFound) {
+			h.cfg.Logger.Warn("tus: delete expired upload", slog.String("id", id), slog.Any("error", err))
+		}
+
+		http.Error(w, "upload expired", http.StatusGone)
+
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// complete runs the completion hook. It writes the error response and returns false if the hook failed.
+func (h *Handler) complete(w http.ResponseWriter, r *http.Request, upload *Upload) bool {
+	if h.cfg.OnComplete == nil {
+		return true
+	}
+
+	content, err := h.cfg.Store.Open(r.Context(), upload.ID)
+	if err != nil {
+		h.internalError(w, "open upload", err)
+		return false
+	}
+	defer content.Close()
+
+	if err = h.cfg.OnComplete(r.Context(), upload, content); err != nil {
+		h.cfg.Logger.Error("tus: completion hook failed", slog.String("id", upload.ID), slog.Any("error", err))
+
+		st := status.Convert(err)
+		http.Error(w, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+
+		return false
+	}
+
+	if h.cfg.DeleteOnComplete {
+		if err = h.cfg.Store.Delete(r.Context(), upload.ID); err != nil {
+			h.cfg.Logger.Warn("tus: delete completed upload", slog.String("id", upload.ID), slog.Any("error", err))
+		}
+	}
+
+	return true
+}
+
+func (h *Handler) internalError(w http.ResponseWriter, op string, err error) {
+	h.cfg.Logger.Error("tus: "+op, slog.Any("error", err))
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// parseMetadata decodes an Upload-Metadata header: comma separated "key base64(value)" pairs,
+// the value being optional.
+func parseMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+
+	meta := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata: empty key")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+
+		meta[key] = string(value)
+	}
+
+	return meta, nil
+}
+
+func formatMetadata(meta map[string]string) string {
+	pairs := make([]string, 0, len(meta))
+	for k, v := range meta {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,180 @@
+package tus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, cfg Config) (*httptest.Server, *Handler) {
+	t.Helper()
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	cfg.Store = store
+	cfg.BasePath = "/uploads"
+
+	h, err := NewHandler(cfg)
+	require.NoError(t, err)
+
+	mux := runtime.NewServeMux()
+	require.NoError(t, h.Register(mux))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, h
+}
+
+func doRequest(t *testing.T, method, url string, body io.Reader, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, body)
+	require.NoError(t, err)
+
+	req.Header.Set(headerTusResumable, Version)
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	return resp
+}
+
+func TestUploadLifecycle(t *testing.T) {
+	var received string
+
+	srv, _ := newTestServer(t, Config{
+		OnComplete: func(_ context.Context, upload *Upload, content io.Reader) error {
+			data, err := io.ReadAll(content)
+			received = upload.Metadata["filename"] + ":" + string(data)
+
+			return err
+		},
+	})
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/uploads", nil, map[string]string{
+		headerUploadLength:   "11",
+		headerUploadMetadata: "filename aGVsbG8udHh0",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(headerUploadExpires))
+
+	location := srv.URL + resp.Header.Get("Location")
+
+	resp = doRequest(t, http.MethodPatch, location, strings.NewReader("hello"), map[string]string{
+		"Content-Type":     offsetOctetStream,
+		headerUploadOffset: "0",
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get(headerUploadOffset))
+
+	resp = doRequest(t, http.MethodPatch, location, strings.NewReader("stale"), map[string]string{
+		"Content-Type":     offsetOctetStream,
+		headerUploadOffset: "0",
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodHead, location, nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get(headerUploadOffset))
+	assert.Equal(t, "11", resp.Header.Get(headerUploadLength))
+
+	resp = doRequest(t, http.MethodPost, location, strings.NewReader(" world"), map[string]string{
+		"Content-Type":       offsetOctetStream,
+		headerUploadOffset:   "5",
+		headerMethodOverride: http.MethodPatch,
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "hello.txt:hello world", received)
+
+	resp = doRequest(t, http.MethodDelete, location, nil, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodHead, location, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestUploadPreconditions(t *testing.T) {
+	srv, h := newTestServer(t, Config{MaxSize: 10})
+
+	resp := doRequest(t, http.MethodOptions, srv.URL+"/uploads", nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, Extensions, resp.Header.Get(headerTusExtension))
+	assert.Equal(t, "10", resp.Header.Get(headerTusMaxSize))
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/uploads", nil, map[string]string{
+		headerUploadLength: "11",
+	})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/uploads", nil, map[string]string{
+		headerTusResumable: "0.2.0",
+		headerUploadLength: "1",
+	})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/uploads", nil, map[string]string{
+		headerUploadLength: "4",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	location := srv.URL + resp.Header.Get("Location")
+
+	h.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+
+	resp = doRequest(t, http.MethodHead, location, nil, nil)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+}
+
+func TestCompletedUploadsDontExpire(t *testing.T) {
+	srv, h := newTestServer(t, Config{})
+
+	create := func() string {
+		resp := doRequest(t, http.MethodPost, srv.URL+"/uploads", nil, map[string]string{headerUploadLength: "4"})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		return srv.URL + resp.Header.Get("Location")
+	}
+
+	completed, unfinished := create(), create()
+
+	resp := doRequest(t, http.MethodPatch, completed, strings.NewReader("data"), map[string]string{
+		"Content-Type":     offsetOctetStream,
+		headerUploadOffset: "0",
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(headerUploadExpires))
+
+	h.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+
+	resp = doRequest(t, http.MethodHead, completed, nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "4", resp.Header.Get(headerUploadOffset))
+	assert.Empty(t, resp.Header.Get(headerUploadExpires))
+
+	// The sweeper agrees with HEAD: only the unfinished upload goes.
+	purged, err := h.PurgeExpired(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	resp = doRequest(t, http.MethodHead, completed, nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodHead, unfinished, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}